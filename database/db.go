@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -47,6 +48,12 @@ func Connect() {
 		log.Fatal("Failed to connect to Postgres:", err)
 	}
 
+	// Kolom uang harus decimal(15,2) sebelum AutoMigrate (lihat migrateMoneyColumns)
+	migrateMoneyColumns()
+
+	// Kolom email_verified_at baru: akun yang sudah ada dianggap terverifikasi
+	backfillVerified := !DB.Migrator().HasColumn(&models.User{}, "EmailVerifiedAt")
+
 	// AutoMigrate semua model
 	if err := DB.AutoMigrate(
 		&models.User{},
@@ -55,9 +62,112 @@ func Connect() {
 		&models.Budget{},
 		&models.Notification{},
 		&models.Backup{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.PasswordResetToken{},
+		&models.EmailVerificationToken{},
+		&models.RecoveryCode{},
+		&models.UserIdentity{},
+		&models.LoginAttempt{},
+		&models.PersonalAccessToken{},
+		&models.BackupSchedule{},
+		&models.Account{},
+		&models.Transfer{},
+		&models.TransactionSplit{},
+		&models.ExchangeRate{},
+		&models.RecurringTransaction{},
+		&models.RecurringOccurrence{},
+		&models.Bill{},
+		&models.BillPayment{},
+		&models.SubscriptionDismissal{},
+		&models.CategoryTemplate{},
 	); err != nil {
 		log.Fatal("Failed to migrate:", err)
 	}
 
+	// Pindahkan tautan Google lama (users.google_sub) ke tabel user_identities
+	if DB.Migrator().HasColumn("users", "google_sub") {
+		if err := DB.Exec(`
+			INSERT INTO user_identities (user_id, provider, subject, email, created_at)
+			SELECT id, 'google', google_sub, email, NOW() FROM users
+			WHERE google_sub IS NOT NULL AND google_sub <> ''
+			ON CONFLICT DO NOTHING
+		`).Error; err != nil {
+			log.Fatal("Failed to migrate google_sub:", err)
+		}
+		if err := DB.Migrator().DropColumn("users", "google_sub"); err != nil {
+			log.Fatal("Failed to drop users.google_sub:", err)
+		}
+	}
+
+	if backfillVerified {
+		if err := DB.Exec("UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL").Error; err != nil {
+			log.Fatal("Failed to backfill email_verified_at:", err)
+		}
+	}
+
+	// Kolom currency baru terisi default IDR; transaksi & transfer di akun ikut mata uang akunnya
+	for _, sql := range []string{
+		`UPDATE transactions t SET currency = a.currency FROM accounts a WHERE t.account_id = a.id AND t.currency <> a.currency`,
+		`UPDATE transfers t SET currency = a.currency FROM accounts a WHERE t.from_account_id = a.id AND t.currency <> a.currency`,
+	} {
+		if err := DB.Exec(sql).Error; err != nil {
+			log.Fatal("Failed to backfill currency:", err)
+		}
+	}
+
+	// ADMIN_EMAILS (dipisah koma) dipromosikan menjadi admin saat start, hanya untuk bootstrap
+	// admin pertama: dilewati bila sudah ada admin (admin yang diturunkan lewat API tidak naik lagi),
+	// dan hanya untuk email yang sudah diverifikasi pemiliknya
+	var admins int64
+	DB.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&admins)
+	if admins == 0 {
+		for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+			if email = strings.TrimSpace(email); email != "" {
+				DB.Model(&models.User{}).
+					Where("LOWER(email) = LOWER(?) AND email_verified_at IS NOT NULL", email).
+					Update("role", models.RoleAdmin)
+			}
+		}
+	}
+
 	log.Println("Postgres connected & migrated successfully!")
 }
+
+// moneyColumns adalah semua kolom yang dipetakan ke money.Amount
+var moneyColumns = map[string][]string{
+	"transactions":           {"amount"},
+	"transaction_splits":     {"amount"},
+	"transfers":              {"amount"},
+	"budgets":                {"limit_amount"},
+	"accounts":               {"opening_balance"},
+	"recurring_transactions": {"amount"},
+	"recurring_occurrences":  {"amount"},
+	"bills":                  {"amount"},
+}
+
+// migrateMoneyColumns mengubah kolom uang yang belum numeric(15,2) (mis. dibuat sebagai
+// float/double di database lama) menjadi numeric(15,2). Nilai dibulatkan ke sen terdekat
+// oleh Postgres, sehingga data lama tetap ada dan terbaca persis oleh money.Amount.
+func migrateMoneyColumns() {
+	for table, columns := range moneyColumns {
+		for _, column := range columns {
+			var col struct {
+				DataType     string
+				NumericScale *int
+			}
+			DB.Raw(`
+				SELECT data_type, numeric_scale FROM information_schema.columns
+				WHERE table_schema = CURRENT_SCHEMA() AND table_name = ? AND column_name = ?
+			`, table, column).Scan(&col)
+			if col.DataType == "" || (col.DataType == "numeric" && col.NumericScale != nil && *col.NumericScale == 2) {
+				continue // tabel belum ada atau sudah benar
+			}
+			sql := fmt.Sprintf(`ALTER TABLE %q ALTER COLUMN %q TYPE numeric(15,2) USING ROUND(%q::numeric, 2)`, table, column, column)
+			if err := DB.Exec(sql).Error; err != nil {
+				log.Fatalf("Failed to migrate %s.%s to numeric(15,2): %v", table, column, err)
+			}
+			log.Printf("Migrated %s.%s from %s to numeric(15,2)", table, column, col.DataType)
+		}
+	}
+}
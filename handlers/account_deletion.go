@@ -0,0 +1,97 @@
+// handlers/account_deletion.go
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"finance/database"
+	"finance/mailer"
+	"finance/models"
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// deletionGracePeriod bisa diatur lewat ACCOUNT_DELETION_GRACE_DAYS (default 30 hari)
+func deletionGracePeriod() time.Duration {
+	if days, err := strconv.Atoi(os.Getenv("ACCOUNT_DELETION_GRACE_DAYS")); err == nil && days >= 0 {
+		return time.Duration(days) * 24 * time.Hour
+	}
+	return 30 * 24 * time.Hour
+}
+
+// DELETE /users/me: jadwalkan penghapusan akun. Login lagi sebelum masa tenggang habis membatalkannya.
+func DeleteMe(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var body struct {
+		Password string `json:"password"`
+		Confirm  bool   `json:"confirm"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
+	}
+	var user models.User
+	if err := database.DB.First(&user, uid).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+	}
+	if user.PasswordHash != "" {
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(body.Password)) != nil {
+			return c.Status(401).JSON(fiber.Map{"error": "password mismatch"})
+		}
+	} else if !body.Confirm {
+		return c.Status(400).JSON(fiber.Map{"error": "confirm must be true"})
+	}
+
+	deleteAt := time.Now().Add(deletionGracePeriod())
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("deletion_scheduled_at", deleteAt).Error; err != nil {
+			return err
+		}
+		if err := revokeUserSessions(tx, user.ID, 0); err != nil {
+			return err
+		}
+		return tx.Model(&models.PersonalAccessToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", time.Now()).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "delete failed"})
+	}
+
+	msg := fmt.Sprintf("Halo %s,\n\nAkun Anda dan seluruh datanya akan dihapus permanen pada %s.\nLogin kembali sebelum tanggal tersebut untuk membatalkan penghapusan.\n",
+		user.Name, deleteAt.Format("02 Jan 2006 15:04"))
+	if err := mailer.Send(mailer.Message{To: user.Email, Subject: "Penghapusan akun dijadwalkan", Body: msg}); err != nil {
+		log.Println("Gagal kirim email penghapusan akun:", err)
+	}
+	return c.JSON(fiber.Map{"message": "account scheduled for deletion", "deletion_scheduled_at": deleteAt})
+}
+
+// cancelScheduledDeletion membatalkan penghapusan akun saat user berhasil login lagi
+func cancelScheduledDeletion(user *models.User) bool {
+	if user.DeletionScheduledAt == nil {
+		return false
+	}
+	if err := database.DB.Model(user).Update("deletion_scheduled_at", nil).Error; err != nil {
+		log.Println("Gagal batalkan penghapusan akun:", err)
+		return false
+	}
+	notif := models.Notification{
+		UserID:    user.ID,
+		Title:     "Account",
+		Message:   "Penghapusan akun dibatalkan karena Anda login kembali.",
+		CreatedAt: time.Now(),
+	}
+	if err := database.DB.Create(&notif).Error; err != nil {
+		log.Println("Gagal simpan notifikasi:", err)
+	}
+	return true
+}
@@ -0,0 +1,290 @@
+// handlers/accounts.go
+package handlers
+
+import (
+	"strings"
+
+	"finance/database"
+	"finance/models"
+	"finance/money"
+	"finance/services"
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func validAccountType(t string) bool {
+	for _, at := range models.AccountTypes {
+		if at == t {
+			return true
+		}
+	}
+	return false
+}
+
+// findAccount memastikan akun ada dan milik user
+func findAccount(uid, id uint) (*models.Account, bool) {
+	var acc models.Account
+	if err := database.DB.Where("id = ? AND user_id = ?", id, uid).First(&acc).Error; err != nil {
+		return nil, false
+	}
+	return &acc, true
+}
+
+// POST /accounts
+func CreateAccount(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	var body struct {
+		Name           string       `json:"name"`
+		Type           string       `json:"type"`
+		OpeningBalance money.Amount `json:"opening_balance"`
+		Currency       string       `json:"currency"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return invalidPayload(c, err)
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	body.Type = strings.ToLower(body.Type)
+	body.Currency = strings.ToUpper(body.Currency)
+	if body.Currency == "" {
+		body.Currency = "IDR"
+	}
+	if body.Name == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "name cannot be empty"})
+	}
+	if !validAccountType(body.Type) {
+		return c.Status(400).JSON(fiber.Map{"error": "type must be one of " + strings.Join(models.AccountTypes, ", ")})
+	}
+	if !services.ValidCurrency(body.Currency) {
+		return c.Status(400).JSON(fiber.Map{"error": "currency must be a 3-letter ISO code"})
+	}
+	// saldo awal boleh negatif (mis. kartu kredit)
+	if body.OpeningBalance > money.Max || body.OpeningBalance < -money.Max {
+		return c.Status(400).JSON(fiber.Map{"error": "opening_balance is too large"})
+	}
+
+	var existing models.Account
+	if err := database.DB.Where("user_id = ? AND LOWER(name) = LOWER(?)", uid, body.Name).First(&existing).Error; err == nil {
+		return c.Status(400).JSON(fiber.Map{"error": "account already exists"})
+	}
+
+	acc := models.Account{
+		UserID:         uid,
+		Name:           body.Name,
+		Type:           body.Type,
+		OpeningBalance: body.OpeningBalance,
+		Currency:       body.Currency,
+		Balance:        body.OpeningBalance,
+	}
+	if err := database.DB.Create(&acc).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "create failed", "detail": err.Error()})
+	}
+	return c.Status(201).JSON(acc)
+}
+
+// GET /accounts: semua akun beserta saldo saat ini
+func GetAccounts(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var accounts []models.Account
+	if err := database.DB.Where("user_id = ?", uid).Order("id").Find(&accounts).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "query failed", "detail": err.Error()})
+	}
+	if err := services.FillBalances(database.DB, uid, accounts); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "query failed", "detail": err.Error()})
+	}
+	return c.JSON(accounts)
+}
+
+// GET /accounts/:id
+func GetAccount(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	acc, ok := findAccount(uid, uint(id))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	accounts := []models.Account{*acc}
+	if err := services.FillBalances(database.DB, uid, accounts); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "query failed", "detail": err.Error()})
+	}
+	return c.JSON(accounts[0])
+}
+
+// PUT /accounts/:id
+func UpdateAccount(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	acc, ok := findAccount(uid, uint(id))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+
+	var body struct {
+		Name           *string       `json:"name"`
+		Type           *string       `json:"type"`
+		OpeningBalance *money.Amount `json:"opening_balance"`
+		Currency       *string       `json:"currency"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return invalidPayload(c, err)
+	}
+	if body.Name != nil {
+		name := strings.TrimSpace(*body.Name)
+		if name == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "name cannot be empty"})
+		}
+		var existing models.Account
+		if err := database.DB.Where("user_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", uid, name, acc.ID).First(&existing).Error; err == nil {
+			return c.Status(400).JSON(fiber.Map{"error": "account already exists"})
+		}
+		acc.Name = name
+	}
+	if body.Type != nil {
+		t := strings.ToLower(*body.Type)
+		if !validAccountType(t) {
+			return c.Status(400).JSON(fiber.Map{"error": "type must be one of " + strings.Join(models.AccountTypes, ", ")})
+		}
+		acc.Type = t
+	}
+	if body.OpeningBalance != nil {
+		if *body.OpeningBalance > money.Max || *body.OpeningBalance < -money.Max {
+			return c.Status(400).JSON(fiber.Map{"error": "opening_balance is too large"})
+		}
+		acc.OpeningBalance = *body.OpeningBalance
+	}
+	if body.Currency != nil {
+		cur := strings.ToUpper(*body.Currency)
+		if !services.ValidCurrency(cur) {
+			return c.Status(400).JSON(fiber.Map{"error": "currency must be a 3-letter ISO code"})
+		}
+		// transaksi lama tercatat dalam mata uang akun, jadi mata uang hanya boleh diganti saat akun masih kosong
+		if cur != acc.Currency {
+			var count int64
+			database.DB.Model(&models.Transaction{}).Where("account_id = ?", acc.ID).Count(&count)
+			if count == 0 {
+				database.DB.Model(&models.RecurringTransaction{}).Where("account_id = ?", acc.ID).Count(&count)
+			}
+			if count == 0 {
+				database.DB.Model(&models.Bill{}).Where("account_id = ?", acc.ID).Count(&count)
+			}
+			if count > 0 {
+				return c.Status(409).JSON(fiber.Map{"error": "cannot change currency of an account with transactions"})
+			}
+		}
+		acc.Currency = cur
+	}
+
+	if err := database.DB.Save(acc).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "update failed", "detail": err.Error()})
+	}
+	accounts := []models.Account{*acc}
+	services.FillBalances(database.DB, uid, accounts)
+	return c.JSON(accounts[0])
+}
+
+// DELETE /accounts/:id: hanya bisa bila tidak ada transaksi di akun tersebut
+func DeleteAccount(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	acc, ok := findAccount(uid, uint(id))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+
+	var count int64
+	database.DB.Model(&models.Transaction{}).Where("account_id = ?", acc.ID).Count(&count)
+	if count > 0 {
+		return c.Status(409).JSON(fiber.Map{"error": "account still has transactions", "transaction_count": count})
+	}
+	database.DB.Model(&models.RecurringTransaction{}).Where("account_id = ?", acc.ID).Count(&count)
+	if count > 0 {
+		return c.Status(409).JSON(fiber.Map{"error": "account is used by recurring transactions", "recurring_count": count})
+	}
+	database.DB.Model(&models.Bill{}).Where("account_id = ?", acc.ID).Count(&count)
+	if count > 0 {
+		return c.Status(409).JSON(fiber.Map{"error": "account is used by bills", "bill_count": count})
+	}
+	if err := database.DB.Delete(acc).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "delete failed", "detail": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "deleted"})
+}
+
+// GET /accounts/:id/transactions: mutasi akun dengan saldo berjalan setelah tiap transaksi
+func GetAccountLedger(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	acc, ok := findAccount(uid, uint(id))
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	limit := c.QueryInt("limit", 50)
+	offset := c.QueryInt("offset", 0)
+
+	var results []struct {
+		ID             uint         `json:"id"`
+		Amount         money.Amount `json:"amount"`
+		Note           string       `json:"note"`
+		Date           string       `json:"date"`
+		CategoryID     uint         `json:"category_id"`
+		CategoryName   string       `json:"category_name"`
+		CategoryType   string       `json:"category_type"`
+		TransferID     *uint        `json:"transfer_id"`
+		RunningBalance money.Amount `json:"running_balance"`
+	}
+
+	// Saldo berjalan dihitung atas seluruh mutasi akun, baru kemudian dipaginasi
+	if err := database.DB.Raw(`
+        SELECT * FROM (
+            SELECT t.id, t.amount, t.note, t.date,
+                   t.category_id, COALESCE(c.name, '') AS category_name,
+                   COALESCE(c.type, 'transfer_' || t.transfer_leg) AS category_type, t.transfer_id,
+                   ? + SUM(`+services.SignedAmountSQL+`) OVER (ORDER BY t.date, t.id) AS running_balance
+            FROM transactions t
+            LEFT JOIN categories c ON t.category_id = c.id
+            WHERE t.user_id = ? AND t.account_id = ?
+        ) ledger
+        ORDER BY ledger.date DESC, ledger.id DESC
+        LIMIT ? OFFSET ?
+    `, acc.OpeningBalance, uid, acc.ID, limit, offset).Scan(&results).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "query failed", "detail": err.Error()})
+	}
+
+	accounts := []models.Account{*acc}
+	services.FillBalances(database.DB, uid, accounts)
+	return c.JSON(fiber.Map{
+		"account":      accounts[0],
+		"transactions": results,
+	})
+}
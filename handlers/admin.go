@@ -0,0 +1,211 @@
+// handlers/admin.go
+package handlers
+
+import (
+	"time"
+
+	"finance/database"
+	"finance/models"
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func adminUserJSON(u models.User) fiber.Map {
+	return fiber.Map{
+		"id":                      u.ID,
+		"name":                    u.Name,
+		"email":                   u.Email,
+		"role":                    u.Role,
+		"email_verified":          u.EmailVerifiedAt != nil,
+		"totp_enabled":            u.TOTPEnabled,
+		"disabled_at":             u.DisabledAt,
+		"password_reset_required": u.PasswordResetRequired,
+		"created_at":              u.CreatedAt,
+	}
+}
+
+// GET /admin/users?q=&role=&limit=&offset=
+func AdminListUsers(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	offset := c.QueryInt("offset", 0)
+
+	query := database.DB.Model(&models.User{})
+	if q := c.Query("q"); q != "" {
+		query = query.Where("name ILIKE ? OR email ILIKE ?", "%"+q+"%", "%"+q+"%")
+	}
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+	if c.QueryBool("disabled") {
+		query = query.Where("disabled_at IS NOT NULL")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var users []models.User
+	if err := query.Order("id").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "query failed", "detail": err.Error()})
+	}
+	results := make([]fiber.Map, 0, len(users))
+	for _, u := range users {
+		results = append(results, adminUserJSON(u))
+	}
+	return c.JSON(fiber.Map{"total": total, "users": results})
+}
+
+// GET /admin/users/:id
+func AdminGetUser(c *fiber.Ctx) error {
+	var user models.User
+	if err := database.DB.First(&user, c.Params("id")).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+	}
+	resp := adminUserJSON(user)
+
+	var transactions, sessions int64
+	database.DB.Model(&models.Transaction{}).Where("user_id = ?", user.ID).Count(&transactions)
+	database.DB.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, time.Now()).Count(&sessions)
+	resp["transaction_count"] = transactions
+	resp["active_sessions"] = sessions
+	return c.JSON(resp)
+}
+
+// adminTarget memuat user target dan menolak aksi admin terhadap akunnya sendiri
+func adminTarget(c *fiber.Ctx) (*models.User, *fiber.Error) {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return nil, fiber.NewError(401, "unauthorized")
+	}
+	var user models.User
+	if err := database.DB.First(&user, c.Params("id")).Error; err != nil {
+		return nil, fiber.NewError(404, "user not found")
+	}
+	if user.ID == uid {
+		return nil, fiber.NewError(400, "cannot perform this action on your own account")
+	}
+	return &user, nil
+}
+
+// PUT /admin/users/:id/role
+func AdminSetRole(c *fiber.Ctx) error {
+	user, ferr := adminTarget(c)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	var body struct {
+		Role string `json:"role"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
+	}
+	if body.Role != models.RoleUser && body.Role != models.RoleAdmin {
+		return c.Status(400).JSON(fiber.Map{"error": "role must be user or admin"})
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("role", body.Role).Error; err != nil {
+			return err
+		}
+		// Claim role di access token yang sudah terbit baru hilang saat sesi dicabut
+		if body.Role != models.RoleAdmin {
+			return revokeUserSessions(tx, user.ID, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "update failed"})
+	}
+	return c.JSON(adminUserJSON(*user))
+}
+
+// POST /admin/users/:id/disable: blokir login dan cabut semua sesi serta personal access token
+func AdminDisableUser(c *fiber.Ctx) error {
+	user, ferr := adminTarget(c)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	now := time.Now()
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("disabled_at", now).Error; err != nil {
+			return err
+		}
+		if err := revokeUserSessions(tx, user.ID, 0); err != nil {
+			return err
+		}
+		return tx.Model(&models.PersonalAccessToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", now).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "disable failed"})
+	}
+	return c.JSON(adminUserJSON(*user))
+}
+
+// POST /admin/users/:id/enable
+func AdminEnableUser(c *fiber.Ctx) error {
+	user, ferr := adminTarget(c)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	if err := database.DB.Model(user).Update("disabled_at", nil).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "enable failed"})
+	}
+	return c.JSON(adminUserJSON(*user))
+}
+
+// POST /admin/users/:id/force-password-reset: login diblokir sampai user mengatur password baru lewat email
+func AdminForcePasswordReset(c *fiber.Ctx) error {
+	user, ferr := adminTarget(c)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("password_reset_required", true).Error; err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, user.ID, 0)
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "force reset failed"})
+	}
+	if err := sendPasswordReset(*user); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "send reset email failed"})
+	}
+	return c.JSON(adminUserJSON(*user))
+}
+
+// GET /admin/stats?days=30: jumlah user dan transaksi per hari
+func AdminStats(c *fiber.Ctx) error {
+	days := c.QueryInt("days", 30)
+	if days < 1 || days > 366 {
+		return c.Status(400).JSON(fiber.Map{"error": "days must be between 1 and 366"})
+	}
+
+	var users, verified, disabled, admins int64
+	database.DB.Model(&models.User{}).Count(&users)
+	database.DB.Model(&models.User{}).Where("email_verified_at IS NOT NULL").Count(&verified)
+	database.DB.Model(&models.User{}).Where("disabled_at IS NOT NULL").Count(&disabled)
+	database.DB.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&admins)
+
+	var perDay []struct {
+		Day   string `json:"day"`
+		Count int64  `json:"count"`
+	}
+	database.DB.Raw(`
+        SELECT TO_CHAR(t.created_at, 'YYYY-MM-DD') AS day, COUNT(*) AS count
+        FROM transactions t
+        WHERE t.created_at >= ?
+        GROUP BY TO_CHAR(t.created_at, 'YYYY-MM-DD')
+        ORDER BY day
+    `, time.Now().AddDate(0, 0, -days)).Scan(&perDay)
+
+	return c.JSON(fiber.Map{
+		"users":                users,
+		"verified_users":       verified,
+		"disabled_users":       disabled,
+		"admins":               admins,
+		"transactions_per_day": perDay,
+	})
+}
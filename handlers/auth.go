@@ -2,24 +2,37 @@
 package handlers
 
 import (
-	"encoding/json"
-	"net/http"
-	"time"
+	"errors"
+	"log"
 
 	"finance/database"
+	"finance/jwtkeys"
 	"finance/models"
+	"finance/services"
+	"finance/utils"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v4"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
+// seedCategories mengisi kategori awal akun baru dari template; locale tanpa template
+// (mis. store template dikosongkan admin) tidak menggagalkan pendaftaran
+func seedCategories(tx *gorm.DB, userID uint, locale string) error {
+	_, err := services.ApplyCategoryTemplate(tx, userID, locale)
+	if errors.Is(err, services.ErrUnknownTemplateLocale) {
+		return nil
+	}
+	return err
+}
+
 // FR-01: Register email/password
 func Register(c *fiber.Ctx) error {
 	var body struct {
 		Name     string `json:"name"`
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Locale   string `json:"locale"` // set template kategori awal; default dari Accept-Language
 	}
 	if err := c.BodyParser(&body); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
@@ -34,10 +47,20 @@ func Register(c *fiber.Ctx) error {
 		Email:        body.Email,
 		PasswordHash: string(hash),
 	}
-	if err := database.DB.Create(&user).Error; err != nil {
+	locale := services.PickTemplateLocale(database.DB, body.Locale+","+c.Get("Accept-Language"))
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return seedCategories(tx, user.ID, locale)
+	})
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "register failed"})
 	}
-	return c.Status(201).JSON(fiber.Map{"id": user.ID, "email": user.Email})
+	if err := sendVerificationEmail(user); err != nil {
+		log.Println("Gagal kirim email verifikasi:", err)
+	}
+	return c.Status(201).JSON(fiber.Map{"id": user.ID, "email": user.Email, "email_verified": false})
 }
 
 // FR-02: Login
@@ -45,72 +68,58 @@ func Login(c *fiber.Ctx) error {
 	var body struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Device   string `json:"device"`
 	}
 	if err := c.BodyParser(&body); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
 	}
 
+	if throttled, err := loginThrottled(c, body.Email); throttled {
+		return err
+	}
+
 	var user models.User
 	if err := database.DB.Where("email = ?", body.Email).First(&user).Error; err != nil {
+		recordLoginFailure(c, body.Email, nil)
 		return c.Status(401).JSON(fiber.Map{"error": "invalid credentials"})
 	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(body.Password)); err != nil {
+		recordLoginFailure(c, body.Email, &user)
 		return c.Status(401).JSON(fiber.Map{"error": "invalid credentials"})
 	}
+	// Dengan 2FA aktif counter baru direset setelah kode 2FA benar
+	if !user.TOTPEnabled {
+		recordLoginSuccess(body.Email)
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"exp":     time.Now().Add(72 * time.Hour).Unix(),
-	})
-	t, _ := token.SignedString([]byte(jwtSecret))
-	return c.JSON(fiber.Map{"token": t, "user": fiber.Map{"id": user.ID, "email": user.Email, "name": user.Name}})
+	return completeLogin(c, user, body.Device, fiber.Map{"id": user.ID, "email": user.Email, "name": user.Name})
 }
 
-// FR-02: Logout (client should delete token; server can implement blacklist if needed)
+// FR-02: Logout, cabut sesi milik refresh token sehingga access token-nya ikut ditolak
 func Logout(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{"message": "logout client-side: delete token"})
-}
-
-// FR: Google login/register
-func GoogleLogin(c *fiber.Ctx) error {
 	var body struct {
-		IDToken  string `json:"id_token"`
-		ClientID string `json:"client_id"` // optional: validate audience
+		RefreshToken string `json:"refresh_token"`
 	}
-	if err := c.BodyParser(&body); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
-	}
-	if body.IDToken == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "id_token required"})
+	if err := c.BodyParser(&body); err != nil || body.RefreshToken == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "refresh_token required"})
 	}
 
-	resp, err := http.Get("https://oauth2.googleapis.com/tokeninfo?id_token=" + body.IDToken)
-	if err != nil || resp.StatusCode != http.StatusOK {
-		return c.Status(401).JSON(fiber.Map{"error": "google token verify failed"})
+	var rt models.RefreshToken
+	if err := database.DB.Where("token_hash = ?", utils.HashToken(body.RefreshToken)).First(&rt).Error; err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "invalid refresh token"})
 	}
-	defer resp.Body.Close()
-
-	var info struct {
-		Aud     string `json:"aud"`
-		Email   string `json:"email"`
-		Name    string `json:"name"`
-		Picture string `json:"picture"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
-		return c.Status(401).JSON(fiber.Map{"error": "invalid google token payload"})
+	if err := revokeSession(database.DB, rt.SessionID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "logout failed"})
 	}
-	// TODO: validate info.Aud == expected ClientID
+	return c.JSON(fiber.Map{"message": "logged out"})
+}
 
-	var user models.User
-	if err := database.DB.Where("email = ?", info.Email).First(&user).Error; err != nil {
-		user = models.User{Name: info.Name, Email: info.Email, PhotoURL: info.Picture}
-		database.DB.Create(&user)
-	}
+// FR: Google login/register (alias untuk POST /auth/oidc/google)
+func GoogleLogin(c *fiber.Ctx) error {
+	return socialLogin(c, "google")
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"exp":     time.Now().Add(72 * time.Hour).Unix(),
-	})
-	t, _ := token.SignedString([]byte(jwtSecret))
-	return c.JSON(fiber.Map{"token": t, "user": fiber.Map{"id": user.ID, "email": user.Email, "name": user.Name, "photo_url": user.PhotoURL}})
+// GET /.well-known/jwks.json: kunci publik untuk verifikasi token oleh service lain
+func JWKS(c *fiber.Ctx) error {
+	return c.JSON(jwtkeys.Keys.JWKS())
 }
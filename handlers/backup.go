@@ -0,0 +1,168 @@
+// handlers/backup.go
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"finance/database"
+	"finance/models"
+	"finance/services"
+	"finance/storage"
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// POST /backups: snapshot kategori, transaksi dan budget ke arsip backup
+func CreateBackup(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	backup, err := services.CreateBackup(database.DB, uid, models.BackupManual)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "backup failed", "detail": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(backup)
+}
+
+// GET /backups
+func GetBackups(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	limit := c.QueryInt("limit", 20)
+	offset := c.QueryInt("offset", 0)
+
+	var backups []models.Backup
+	if err := database.DB.Where("user_id = ?", uid).Order("created_at desc").Limit(limit).Offset(offset).Find(&backups).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "query failed", "detail": err.Error()})
+	}
+	return c.JSON(backups)
+}
+
+// POST /backups/:id/restore: ganti data saat ini dengan isi backup (atomik)
+func RestoreBackup(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var backup models.Backup
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Params("id"), uid).First(&backup).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not found"})
+	}
+
+	archive, err := services.LoadBackup(backup)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return c.Status(fiber.StatusGone).JSON(fiber.Map{"error": "backup archive missing"})
+	case errors.Is(err, services.ErrBackupVersion):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "unsupported backup version"})
+	case errors.Is(err, services.ErrBackupCorrupt):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "backup archive is corrupt"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "restore failed", "detail": err.Error()})
+	}
+
+	if err := services.RestoreBackup(database.DB, uid, archive); err != nil {
+		if errors.Is(err, services.ErrBackupCorrupt) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "backup archive is corrupt"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "restore failed", "detail": err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"message":      "backup restored",
+		"accounts":     len(archive.Accounts),
+		"categories":   len(archive.Categories),
+		"transactions": len(archive.Transactions),
+		"transfers":    len(archive.Transfers),
+		"budgets":      len(archive.Budgets),
+		"recurring":    len(archive.Recurring),
+		"bills":        len(archive.Bills),
+	})
+}
+
+// GET /backups/schedule
+func GetBackupSchedule(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var s models.BackupSchedule
+	if err := database.DB.Where("user_id = ?", uid).First(&s).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no backup schedule"})
+	}
+	return c.JSON(s)
+}
+
+// PUT /backups/schedule: buat atau ubah jadwal backup otomatis
+func PutBackupSchedule(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	s := models.BackupSchedule{UserID: uid, Hour: 2, Timezone: "Asia/Jakarta", KeepRecent: 7, KeepMonthly: 12, Enabled: true}
+	database.DB.Where("user_id = ?", uid).First(&s)
+
+	var body struct {
+		Frequency   *string `json:"frequency"`
+		Weekday     *int    `json:"weekday"`
+		Hour        *int    `json:"hour"`
+		Timezone    *string `json:"timezone"`
+		KeepRecent  *int    `json:"keep_recent"`
+		KeepMonthly *int    `json:"keep_monthly"`
+		Enabled     *bool   `json:"enabled"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+	}
+	if body.Frequency != nil {
+		s.Frequency = *body.Frequency
+	}
+	if body.Weekday != nil {
+		s.Weekday = *body.Weekday
+	}
+	if body.Hour != nil {
+		s.Hour = *body.Hour
+	}
+	if body.Timezone != nil {
+		s.Timezone = *body.Timezone
+	}
+	if body.KeepRecent != nil {
+		s.KeepRecent = *body.KeepRecent
+	}
+	if body.KeepMonthly != nil {
+		s.KeepMonthly = *body.KeepMonthly
+	}
+	if body.Enabled != nil {
+		s.Enabled = *body.Enabled
+	}
+	if err := services.ValidateSchedule(&s); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	s.NextRunAt = services.NextBackupRun(s, time.Now())
+
+	if err := database.DB.Save(&s).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "save failed", "detail": err.Error()})
+	}
+	return c.JSON(s)
+}
+
+// DELETE /backups/schedule: matikan backup otomatis (backup yang sudah ada tidak dihapus)
+func DeleteBackupSchedule(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	res := database.DB.Where("user_id = ?", uid).Delete(&models.BackupSchedule{})
+	if res.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "delete failed", "detail": res.Error.Error()})
+	}
+	if res.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "no backup schedule"})
+	}
+	return c.JSON(fiber.Map{"message": "backup schedule deleted"})
+}
@@ -0,0 +1,432 @@
+// handlers/bills.go
+package handlers
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"finance/database"
+	"finance/models"
+	"finance/money"
+	"finance/services"
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// billBody dipakai untuk create (field wajib dicek) dan update (nil = tidak diubah)
+type billBody struct {
+	Payee      *string       `json:"payee"`
+	Amount     *money.Amount `json:"amount"`
+	Currency   *string       `json:"currency"`
+	CategoryID *uint         `json:"category_id"`
+	AccountID  *uint         `json:"account_id"` // 0 = tanpa akun
+	Note       *string       `json:"note"`
+	AutoPay    *bool         `json:"auto_pay"`
+	RemindDays *int          `json:"remind_days"` // default 3
+	Frequency  *string       `json:"frequency"`   // daily, weekly, monthly
+	Interval   *int          `json:"interval"`
+	MonthDay   *int          `json:"month_day"`  // monthly: 1..31, -1 = akhir bulan
+	StartDate  *string       `json:"start_date"` // jatuh tempo pertama, RFC3339 atau YYYY-MM-DD
+	Until      *string       `json:"until"`      // YYYY-MM-DD, "" = tanpa tanggal akhir
+	Count      *int          `json:"count"`      // 0 = tanpa batas
+}
+
+// applyBillBody menyalin body ke tagihan lalu memvalidasinya seperti transaksi biasa.
+// Mengembalikan pesan error untuk response 400, "" bila valid.
+func applyBillBody(uid uint, b *models.Bill, body billBody) string {
+	if body.Payee != nil {
+		b.Payee = *body.Payee
+	}
+	if body.Amount != nil {
+		b.Amount = *body.Amount
+	}
+	if body.CategoryID != nil {
+		b.CategoryID = *body.CategoryID
+	}
+	if body.AccountID != nil {
+		if *body.AccountID == 0 {
+			b.AccountID = nil
+		} else {
+			b.AccountID = body.AccountID
+		}
+		if body.Currency == nil {
+			b.Currency = "" // ikut mata uang akun yang baru
+		}
+	}
+	if body.Currency != nil {
+		b.Currency = *body.Currency
+	}
+	if body.Note != nil {
+		b.Note = *body.Note
+	}
+	if body.AutoPay != nil {
+		b.AutoPay = *body.AutoPay
+	}
+	if body.RemindDays != nil {
+		b.RemindDays = *body.RemindDays
+	}
+	if body.Frequency != nil {
+		b.Frequency = *body.Frequency
+	}
+	if body.Interval != nil {
+		b.Interval = *body.Interval
+	}
+	if body.MonthDay != nil {
+		b.MonthDay = *body.MonthDay
+	}
+	if body.StartDate != nil {
+		sd, err := parseRecurringDate(*body.StartDate)
+		if err != nil {
+			return "invalid start_date"
+		}
+		b.StartDate = sd
+	}
+	if body.Until != nil {
+		if *body.Until == "" {
+			b.Until = nil
+		} else {
+			until, err := time.Parse("2006-01-02", *body.Until)
+			if err != nil {
+				return "invalid until"
+			}
+			b.Until = &until
+		}
+	}
+	if body.Count != nil {
+		b.Count = *body.Count
+	}
+
+	if b.Payee == "" {
+		return "payee cannot be empty"
+	}
+	if msg := checkAmount("amount", b.Amount); msg != "" {
+		return msg
+	}
+	if b.RemindDays < 0 || b.RemindDays > 60 {
+		return "remind_days must be between 0 and 60"
+	}
+	trx, _, err := services.BuildTransaction(database.DB, uid, services.NewTransaction{
+		CategoryID: b.CategoryID,
+		AccountID:  b.AccountID,
+		Amount:     b.Amount,
+		Note:       b.Payee,
+		Currency:   b.Currency,
+	})
+	if err != nil {
+		return err.Error()
+	}
+	b.Currency = trx.Currency
+	if err := services.ValidateRecurrence(&b.Recurrence); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// findBill memastikan tagihan ada dan milik user
+func findBill(c *fiber.Ctx, uid uint) (*models.Bill, bool) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return nil, false
+	}
+	var b models.Bill
+	if err := database.DB.Where("id = ? AND user_id = ?", id, uid).First(&b).Error; err != nil {
+		return nil, false
+	}
+	return &b, true
+}
+
+// POST /bills
+func CreateBill(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var body billBody
+	if err := c.BodyParser(&body); err != nil {
+		return invalidPayload(c, err)
+	}
+	if body.Payee == nil || body.Amount == nil || body.CategoryID == nil || body.Frequency == nil || body.StartDate == nil {
+		return c.Status(400).JSON(fiber.Map{"error": "payee, amount, category_id, frequency and start_date are required"})
+	}
+
+	b := models.Bill{UserID: uid, RemindDays: 3, Recurrence: models.Recurrence{Interval: 1}}
+	if msg := applyBillBody(uid, &b, body); msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
+	}
+	b.NextDueAt = services.NextRunFor(&b.Recurrence, 0)
+	if err := database.DB.Create(&b).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "create failed", "detail": err.Error()})
+	}
+	return c.Status(201).JSON(b)
+}
+
+// GET /bills
+func GetBills(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var bills []models.Bill
+	if err := database.DB.Where("user_id = ?", uid).Order("next_due_at IS NULL, next_due_at, id").Find(&bills).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "query failed", "detail": err.Error()})
+	}
+	return c.JSON(bills)
+}
+
+// GET /bills/:id: tagihan beserta riwayat pembayaran
+func GetBill(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	b, ok := findBill(c, uid)
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	var payments []models.BillPayment
+	database.DB.Where("bill_id = ?", b.ID).Order("due_date DESC").Find(&payments)
+	return c.JSON(fiber.Map{"bill": b, "payments": payments})
+}
+
+// PUT /bills/:id: bila aturan jadwal berubah, jatuh tempo berikutnya dihitung ulang
+// setelah jatuh tempo terakhir yang sudah dibayar
+func UpdateBill(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var body billBody
+	if err := c.BodyParser(&body); err != nil {
+		return invalidPayload(c, err)
+	}
+	b, ok := findBill(c, uid)
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+
+	var msg string
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// kunci tagihan agar job pengingat/auto-pay tidak berjalan di tengah perubahan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(b, b.ID).Error; err != nil {
+			return err
+		}
+		old := *b
+		if msg = applyBillBody(uid, b, body); msg != "" {
+			return nil
+		}
+		ruleChanged := b.Frequency != old.Frequency || b.Interval != old.Interval ||
+			b.MonthDay != old.MonthDay || !b.StartDate.Equal(old.StartDate)
+		if ruleChanged {
+			b.NextIndex = 0
+			if old.NextIndex > 0 {
+				lastPaid := services.OccurrenceDate(&old.Recurrence, old.NextIndex-1)
+				b.NextIndex = services.FirstIndexAfter(&b.Recurrence, lastPaid)
+			}
+			b.RemindedIndex = b.NextIndex
+			b.OverdueIndex = b.NextIndex
+		}
+		b.NextDueAt = services.NextRunFor(&b.Recurrence, b.NextIndex)
+		return tx.Save(b).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "update failed", "detail": err.Error()})
+	}
+	if msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
+	}
+	return c.JSON(b)
+}
+
+// DELETE /bills/:id: transaksi pembayaran yang sudah dibuat tetap ada
+func DeleteBill(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	b, ok := findBill(c, uid)
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("bill_id = ?", b.ID).Delete(&models.BillPayment{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(b).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "delete failed", "detail": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "deleted"})
+}
+
+// POST /bills/:id/pay: tandai jatuh tempo tertua yang belum dibayar sebagai lunas.
+// Transaksi dibuat lewat jalur yang sama dengan POST /transactions.
+// Body opsional: {"amount": .., "account_id": .., "date": RFC3339}
+func PayBill(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var body struct {
+		Amount    *money.Amount `json:"amount"`
+		AccountID *uint         `json:"account_id"`
+		Date      *string       `json:"date"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return invalidPayload(c, err)
+		}
+	}
+	b, ok := findBill(c, uid)
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+
+	opt := services.BillPayOptions{Amount: body.Amount, AccountID: body.AccountID}
+	if body.Amount != nil {
+		if msg := checkAmount("amount", *body.Amount); msg != "" {
+			return c.Status(400).JSON(fiber.Map{"error": msg})
+		}
+	}
+	if body.Date != nil {
+		parsed, err := time.Parse(time.RFC3339, *body.Date)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid date"})
+		}
+		opt.Date = &parsed
+	}
+
+	payment, trx, err := services.PayBill(database.DB, b.ID, opt)
+	switch {
+	case errors.Is(err, services.ErrBillSettled):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	case services.IsTransactionInputError(err):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(500).JSON(fiber.Map{"error": "payment failed", "detail": err.Error()})
+	}
+	database.DB.First(b, b.ID)
+	return c.Status(201).JSON(fiber.Map{"payment": payment, "transaction": trx, "bill": b})
+}
+
+// billCalendarItem adalah satu jatuh tempo pada kalender tagihan
+type billCalendarItem struct {
+	BillID        uint         `json:"bill_id"`
+	Payee         string       `json:"payee"`
+	Amount        money.Amount `json:"amount"`
+	Currency      string       `json:"currency"`
+	AccountID     *uint        `json:"account_id"`
+	AutoPay       bool         `json:"auto_pay"`
+	DueDate       string       `json:"due_date"`
+	Status        string       `json:"status"` // paid, overdue, due_soon, upcoming
+	TransactionID *uint        `json:"transaction_id,omitempty"`
+}
+
+// GET /bills/upcoming?from=YYYY-MM-DD&to=YYYY-MM-DD (default: hari ini s/d 30 hari ke depan)
+// Kalender jatuh tempo per tanggal. Jatuh tempo yang belum dibayar sebelum "from" ikut sebagai overdue.
+func GetUpcomingBills(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from, to := today, today.AddDate(0, 0, 30)
+	if s := c.Query("from"); s != "" {
+		if from, err = time.Parse("2006-01-02", s); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid from"})
+		}
+	}
+	if s := c.Query("to"); s != "" {
+		if to, err = time.Parse("2006-01-02", s); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid to"})
+		}
+	}
+	if to.Before(from) || to.Sub(from) > 366*24*time.Hour {
+		return c.Status(400).JSON(fiber.Map{"error": "range must be between 0 and 366 days"})
+	}
+
+	var bills []models.Bill
+	database.DB.Where("user_id = ?", uid).Find(&bills)
+	billByID := map[uint]models.Bill{}
+	items := []billCalendarItem{}
+	item := func(b models.Bill, due time.Time, status string) billCalendarItem {
+		return billCalendarItem{
+			BillID: b.ID, Payee: b.Payee, Amount: b.Amount, Currency: b.Currency, AccountID: b.AccountID,
+			AutoPay: b.AutoPay, DueDate: due.Format("2006-01-02"), Status: status,
+		}
+	}
+
+	for _, b := range bills {
+		billByID[b.ID] = b
+		if b.NextDueAt == nil {
+			continue
+		}
+		for n := b.NextIndex; ; n++ {
+			date := services.NextRunFor(&b.Recurrence, n)
+			if date == nil {
+				break
+			}
+			// dibandingkan per hari di zona waktu jadwal tagihan
+			day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+			local := now.In(date.Location())
+			today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+			if day.After(to) {
+				break
+			}
+			status := "upcoming"
+			switch {
+			case day.Before(today):
+				status = "overdue"
+			case !day.After(today.AddDate(0, 0, b.RemindDays)):
+				status = "due_soon"
+			}
+			if day.Before(from) && status != "overdue" {
+				continue
+			}
+			items = append(items, item(b, day, status))
+		}
+	}
+
+	var payments []models.BillPayment
+	database.DB.Where("user_id = ? AND due_date BETWEEN ? AND ?", uid, from.Format("2006-01-02"), to.Format("2006-01-02")).Find(&payments)
+	for _, p := range payments {
+		b, ok := billByID[p.BillID]
+		if !ok {
+			continue
+		}
+		it := item(b, p.DueDate, "paid")
+		it.TransactionID = p.TransactionID
+		items = append(items, it)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].DueDate != items[j].DueDate {
+			return items[i].DueDate < items[j].DueDate
+		}
+		return items[i].BillID < items[j].BillID
+	})
+	days := []fiber.Map{}
+	overdue := 0
+	for i := 0; i < len(items); {
+		j := i
+		for j < len(items) && items[j].DueDate == items[i].DueDate {
+			if items[j].Status == "overdue" {
+				overdue++
+			}
+			j++
+		}
+		days = append(days, fiber.Map{"date": items[i].DueDate, "bills": items[i:j]})
+		i = j
+	}
+	return c.JSON(fiber.Map{
+		"from":          from.Format("2006-01-02"),
+		"to":            to.Format("2006-01-02"),
+		"overdue_count": overdue,
+		"days":          days,
+	})
+}
@@ -3,22 +3,14 @@ package handlers
 import (
 	"finance/database"
 	"finance/models"
+	"finance/money"
+	"finance/services"
 	"finance/utils"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// helper untuk status budget agar konsisten
-func calculateStatus(total, limit float64) string {
-	if total >= limit {
-		return "Over Budget"
-	} else if total >= limit*0.8 {
-		return "Near Limit"
-	}
-	return "Safe"
-}
-
 // CreateBudget
 func CreateBudget(c *fiber.Ctx) error {
 	uid, err := utils.GetUserID(c)
@@ -27,13 +19,17 @@ func CreateBudget(c *fiber.Ctx) error {
 	}
 
 	var body struct {
-		CategoryID  uint    `json:"category_id"`
-		LimitAmount float64 `json:"limit_amount"`
-		StartDate   string  `json:"start_date"`
-		EndDate     string  `json:"end_date"`
+		CategoryID  uint         `json:"category_id"`
+		LimitAmount money.Amount `json:"limit_amount"`
+		StartDate   string       `json:"start_date"`
+		EndDate     string       `json:"end_date"`
 	}
 	if err := c.BodyParser(&body); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+		return invalidPayload(c, err)
+	}
+
+	if msg := checkAmount("limit_amount", body.LimitAmount); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
 	}
 
 	// validasi kategori
@@ -42,6 +38,9 @@ func CreateBudget(c *fiber.Ctx) error {
 		if err := database.DB.Where("id = ? AND user_id = ?", body.CategoryID, uid).First(&cat).Error; err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid category"})
 		}
+		if cat.ArchivedAt != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": services.ErrArchivedCategory.Error()})
+		}
 	}
 
 	// validasi tanggal
@@ -100,15 +99,18 @@ func UpdateBudget(c *fiber.Ctx) error {
 	}
 
 	var body struct {
-		LimitAmount *float64 `json:"limit_amount"`
-		StartDate   *string  `json:"start_date"`
-		EndDate     *string  `json:"end_date"`
+		LimitAmount *money.Amount `json:"limit_amount"`
+		StartDate   *string       `json:"start_date"`
+		EndDate     *string       `json:"end_date"`
 	}
 	if err := c.BodyParser(&body); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid payload"})
+		return invalidPayload(c, err)
 	}
 
 	if body.LimitAmount != nil {
+		if msg := checkAmount("limit_amount", *body.LimitAmount); msg != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+		}
 		b.LimitAmount = *body.LimitAmount
 	}
 	if body.StartDate != nil {
@@ -157,30 +159,38 @@ func GetBudgetStatus(c *fiber.Ctx) error {
 	}
 
 	var results []struct {
-		BudgetID     uint    `json:"budget_id"`
-		CategoryName string  `json:"category_name"`
-		LimitAmount  float64 `json:"limit_amount"`
-		TotalExpense float64 `json:"total_expense"`
-		Status       string  `json:"status"`
+		BudgetID     uint         `json:"budget_id"`
+		CategoryName string       `json:"category_name"`
+		LimitAmount  money.Amount `json:"limit_amount"`
+		TotalExpense money.Amount `json:"total_expense"`
+		Status       string       `json:"status"`
 	}
 
+	// limit budget dalam mata uang dasar user; transaksi dikonversi dengan kurs di tanggal transaksi.
+	// Budget kategori induk ikut menghitung pengeluaran semua subkategorinya.
+	base, _ := reportCurrency(c, uid)
+
 	database.DB.Raw(`
-        SELECT b.id AS budget_id,
-               c.name AS category_name,
-               b.limit_amount,
-               COALESCE(SUM(t.amount),0) AS total_expense,
+        SELECT s.budget_id, s.category_name, s.limit_amount, s.total_expense,
                CASE 
-                 WHEN COALESCE(SUM(t.amount),0) >= b.limit_amount THEN 'Over Budget'
-                 WHEN COALESCE(SUM(t.amount),0) >= b.limit_amount * 0.8 THEN 'Near Limit'
+                 WHEN s.total_expense >= s.limit_amount THEN 'Over Budget'
+                 WHEN s.total_expense >= s.limit_amount * 0.8 THEN 'Near Limit'
                  ELSE 'Safe'
                END AS status
-        FROM budgets b
-        LEFT JOIN categories c ON b.category_id = c.id
-        LEFT JOIN transactions t ON t.category_id = b.category_id
-           AND t.user_id = b.user_id
-           AND t.date BETWEEN b.start_date AND b.end_date
-        WHERE b.user_id = ?
-        GROUP BY b.id, c.name, b.limit_amount
+        FROM (
+            SELECT b.id AS budget_id,
+                   c.name AS category_name,
+                   b.limit_amount,
+                   COALESCE(SUM(`+services.ConvertSQL("tl.amount", "tl.currency", "tl.date", base)+`),0) AS total_expense
+            FROM budgets b
+            LEFT JOIN categories c ON b.category_id = c.id
+            LEFT JOIN `+services.CategoryLinesSQL+` tl ON `+services.InCategoryTreeSQL("tl.category_id", "b.category_id")+`
+               AND tl.user_id = b.user_id
+               AND tl.date BETWEEN b.start_date AND b.end_date
+               AND tl.transfer_id IS NULL
+            WHERE b.user_id = ?
+            GROUP BY b.id, c.name, b.limit_amount
+        ) s
     `, uid).Scan(&results)
 
 	return c.JSON(results)
@@ -199,14 +209,34 @@ func GetBudgetDetail(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not found"})
 	}
 
-	var transactions []models.Transaction
-	database.DB.Where("user_id = ? AND category_id = ? AND date BETWEEN ? AND ?", uid, budget.CategoryID, budget.StartDate, budget.EndDate).Find(&transactions)
+	// transaksi split masuk bila salah satu barisnya di kategori budget (atau subkategorinya);
+	// yang dihitung hanya baris itu
+	base, _ := reportCurrency(c, uid)
+	var lines []struct {
+		TransactionID uint
+		Amount        money.Amount
+	}
+	database.DB.Raw(`
+        SELECT tl.transaction_id, `+services.ConvertSQL("tl.amount", "tl.currency", "tl.date", base)+` AS amount
+        FROM `+services.CategoryLinesSQL+` tl
+        WHERE tl.user_id = ? AND `+services.InCategoryTreeSQL("tl.category_id", "?")+` AND tl.date BETWEEN ? AND ? AND tl.transfer_id IS NULL
+    `, uid, budget.CategoryID, budget.StartDate, budget.EndDate).Scan(&lines)
 
-	var total float64
-	for _, t := range transactions {
-		total += t.Amount
+	var total money.Amount
+	lineAmounts := map[uint]money.Amount{}
+	ids := []uint{}
+	for _, l := range lines {
+		total += l.Amount
+		if _, seen := lineAmounts[l.TransactionID]; !seen {
+			ids = append(ids, l.TransactionID)
+		}
+		lineAmounts[l.TransactionID] += l.Amount
+	}
+	transactions := []models.Transaction{}
+	if len(ids) > 0 {
+		database.DB.Where("id IN ?", ids).Order("date").Find(&transactions)
 	}
-	status := calculateStatus(total, budget.LimitAmount)
+	status := services.BudgetStatus(total, budget.LimitAmount)
 
 	var cat models.Category
 	database.DB.First(&cat, budget.CategoryID)
@@ -215,7 +245,9 @@ func GetBudgetDetail(c *fiber.Ctx) error {
 		"budget":        budget,
 		"category_name": cat.Name,
 		"transactions":  transactions,
+		"line_amounts":  lineAmounts,
 		"total_expense": total,
+		"base_currency": base,
 		"status":        status,
 	})
 }
@@ -228,23 +260,25 @@ func GetBudgetSummary(c *fiber.Ctx) error {
 	}
 
 	var result struct {
-		TotalLimit   float64 `json:"total_limit"`
-		TotalExpense float64 `json:"total_expense"`
+		TotalLimit   money.Amount `json:"total_limit"`
+		TotalExpense money.Amount `json:"total_expense"`
 	}
+	base, _ := reportCurrency(c, uid)
 	database.DB.Raw(`
         SELECT COALESCE(SUM(b.limit_amount),0) AS total_limit,
-               COALESCE(SUM(t.amount),0) AS total_expense
+               COALESCE(SUM(`+services.ConvertSQL("tl.amount", "tl.currency", "tl.date", base)+`),0) AS total_expense
         FROM budgets b
-        LEFT JOIN transactions t ON t.category_id = b.category_id
-           AND t.user_id = b.user_id
-           AND t.date BETWEEN b.start_date AND b.end_date
+        LEFT JOIN `+services.CategoryLinesSQL+` tl ON `+services.InCategoryTreeSQL("tl.category_id", "b.category_id")+`
+           AND tl.user_id = b.user_id
+           AND tl.date BETWEEN b.start_date AND b.end_date
+           AND tl.transfer_id IS NULL
         WHERE b.user_id = ?
     `, uid).Scan(&result)
 
 	// hitung persentase total penggunaan
 	percentUsed := 0.0
 	if result.TotalLimit > 0 {
-		percentUsed = (result.TotalExpense / result.TotalLimit) * 100
+		percentUsed = result.TotalExpense.Percent(result.TotalLimit)
 	}
 
 	return c.JSON(fiber.Map{
@@ -2,12 +2,15 @@
 package handlers
 
 import (
+	"errors"
 	"finance/database"
 	"finance/models"
+	"finance/services"
 	"finance/utils"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 // FR-06..FR-08
@@ -18,8 +21,9 @@ func CreateCategory(c *fiber.Ctx) error {
 	}
 
 	var body struct {
-		Name string `json:"name"`
-		Type string `json:"type"` // income/expense
+		Name     string `json:"name"`
+		Type     string `json:"type"`      // income/expense
+		ParentID *uint  `json:"parent_id"` // opsional: jadikan subkategori
 	}
 	if err := c.BodyParser(&body); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
@@ -33,22 +37,46 @@ func CreateCategory(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "name cannot be empty"})
 	}
 
-	// Cek duplikat kategori untuk user
-	var existing models.Category
-	if err := database.DB.Where("user_id = ? AND LOWER(name) = LOWER(?) AND type = ?", uid, body.Name, body.Type).First(&existing).Error; err == nil {
+	cat := models.Category{UserID: uid, Name: body.Name, Type: body.Type}
+	if body.ParentID != nil && *body.ParentID != 0 {
+		if err := services.ValidateCategoryParent(database.DB, uid, &cat, *body.ParentID); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		cat.ParentID = body.ParentID
+	}
+
+	// Cek duplikat kategori untuk user (nama unik di antara kategori dengan induk yang sama)
+	if categoryNameTaken(uid, &cat) {
 		return c.Status(400).JSON(fiber.Map{"error": "category already exists"})
 	}
 
-	cat := models.Category{UserID: uid, Name: body.Name, Type: body.Type}
 	if err := database.DB.Create(&cat).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "create failed", "detail": err.Error()})
 	}
 
-	return c.Status(201).JSON(fiber.Map{
-		"id":   cat.ID,
-		"name": cat.Name,
-		"type": cat.Type,
-	})
+	return c.Status(201).JSON(categoryResponse(cat))
+}
+
+// categoryNameTaken: sudah ada kategori lain bernama sama, bertipe sama, di bawah induk yang sama
+func categoryNameTaken(uid uint, cat *models.Category) bool {
+	q := database.DB.Where("user_id = ? AND LOWER(name) = LOWER(?) AND type = ? AND id <> ?", uid, cat.Name, cat.Type, cat.ID)
+	if cat.ParentID == nil {
+		q = q.Where("parent_id IS NULL")
+	} else {
+		q = q.Where("parent_id = ?", *cat.ParentID)
+	}
+	var existing models.Category
+	return q.First(&existing).Error == nil
+}
+
+func categoryResponse(cat models.Category) fiber.Map {
+	return fiber.Map{
+		"id":          cat.ID,
+		"name":        cat.Name,
+		"type":        cat.Type,
+		"parent_id":   cat.ParentID,
+		"archived_at": cat.ArchivedAt,
+	}
 }
 
 func GetCategories(c *fiber.Ctx) error {
@@ -57,12 +85,21 @@ func GetCategories(c *fiber.Ctx) error {
 		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
 	}
 
+	// kategori arsip disembunyikan dari pilihan; ?include_archived=true untuk menampilkannya
+	query := database.DB.Where("user_id = ?", uid)
+	if !c.QueryBool("include_archived") {
+		query = query.Where("archived_at IS NULL")
+	}
 	var cats []models.Category
-	if err := database.DB.Where("user_id = ?", uid).Find(&cats).Error; err != nil {
+	if err := query.Order("name").Find(&cats).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "query failed", "detail": err.Error()})
 	}
 
-	return c.JSON(cats)
+	// default berupa pohon (Children); ?flat=true untuk daftar datar dengan ParentID
+	if c.QueryBool("flat") {
+		return c.JSON(cats)
+	}
+	return c.JSON(services.BuildCategoryTree(cats))
 }
 
 func UpdateCategory(c *fiber.Ctx) error {
@@ -78,8 +115,9 @@ func UpdateCategory(c *fiber.Ctx) error {
 	}
 
 	var body struct {
-		Name *string `json:"name"`
-		Type *string `json:"type"`
+		Name     *string `json:"name"`
+		Type     *string `json:"type"`
+		ParentID *uint   `json:"parent_id"` // 0 = jadikan kategori utama
 	}
 	if err := c.BodyParser(&body); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
@@ -89,11 +127,6 @@ func UpdateCategory(c *fiber.Ctx) error {
 		if strings.TrimSpace(*body.Name) == "" {
 			return c.Status(400).JSON(fiber.Map{"error": "name cannot be empty"})
 		}
-		// Cek duplikat nama kategori
-		var existing models.Category
-		if err := database.DB.Where("user_id = ? AND LOWER(name) = LOWER(?) AND type = ?", uid, *body.Name, cat.Type).First(&existing).Error; err == nil && existing.ID != cat.ID {
-			return c.Status(400).JSON(fiber.Map{"error": "category already exists"})
-		}
 		cat.Name = *body.Name
 	}
 	if body.Type != nil {
@@ -101,18 +134,39 @@ func UpdateCategory(c *fiber.Ctx) error {
 		if t != "income" && t != "expense" {
 			return c.Status(400).JSON(fiber.Map{"error": "type must be income or expense"})
 		}
+		// tipe subkategori selalu ikut induknya
+		if t != cat.Type {
+			var children int64
+			database.DB.Model(&models.Category{}).Where("parent_id = ?", cat.ID).Count(&children)
+			if children > 0 {
+				return c.Status(400).JSON(fiber.Map{"error": "cannot change type of a category with subcategories"})
+			}
+		}
 		cat.Type = t
 	}
+	if body.ParentID != nil {
+		if *body.ParentID == 0 {
+			cat.ParentID = nil
+		} else {
+			cat.ParentID = body.ParentID
+		}
+	}
+	if cat.ParentID != nil && (body.ParentID != nil || body.Type != nil) {
+		if err := services.ValidateCategoryParent(database.DB, uid, &cat, *cat.ParentID); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	// Cek duplikat nama kategori
+	if categoryNameTaken(uid, &cat) {
+		return c.Status(400).JSON(fiber.Map{"error": "category already exists"})
+	}
 
 	if err := database.DB.Save(&cat).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "update failed", "detail": err.Error()})
 	}
 
-	return c.JSON(fiber.Map{
-		"id":   cat.ID,
-		"name": cat.Name,
-		"type": cat.Type,
-	})
+	return c.JSON(categoryResponse(cat))
 }
 
 func DeleteCategory(c *fiber.Ctx) error {
@@ -125,8 +179,21 @@ func DeleteCategory(c *fiber.Ctx) error {
 	// Cek apakah kategori masih dipakai di transaksi
 	var count int64
 	database.DB.Model(&models.Transaction{}).Where("category_id = ? AND user_id = ?", id, uid).Count(&count)
+	if count == 0 {
+		database.DB.Model(&models.TransactionSplit{}).Where("category_id = ? AND user_id = ?", id, uid).Count(&count)
+	}
+	if count == 0 {
+		database.DB.Model(&models.RecurringTransaction{}).Where("category_id = ? AND user_id = ?", id, uid).Count(&count)
+	}
+	if count == 0 {
+		database.DB.Model(&models.Bill{}).Where("category_id = ? AND user_id = ?", id, uid).Count(&count)
+	}
 	if count > 0 {
-		return c.Status(400).JSON(fiber.Map{"error": "category is in use"})
+		return c.Status(400).JSON(fiber.Map{"error": "category is in use, merge or archive it instead"})
+	}
+	database.DB.Model(&models.Category{}).Where("parent_id = ? AND user_id = ?", id, uid).Count(&count)
+	if count > 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "category has subcategories"})
 	}
 
 	tx := database.DB.Where("id = ? AND user_id = ?", id, uid).Delete(&models.Category{})
@@ -139,3 +206,96 @@ func DeleteCategory(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{"message": "deleted"})
 }
+
+// POST /categories/:id/merge {"target_id": 5}
+// Pindahkan semua transaksi, budget, template recurring dan tagihan ke kategori target secara
+// atomik, lalu hapus kategori ini. Subkategorinya pindah ke bawah target.
+func MergeCategory(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	var body struct {
+		TargetID uint `json:"target_id"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
+	}
+	if body.TargetID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "target_id is required"})
+	}
+
+	var moved map[string]int64
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		moved, err = services.MergeCategories(tx, uid, uint(id), body.TargetID)
+		return err
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	case errors.Is(err, services.ErrMergeSameCategory), errors.Is(err, services.ErrMergeType),
+		errors.Is(err, services.ErrMergeDescendant), errors.Is(err, services.ErrMergeTarget),
+		errors.Is(err, services.ErrMergeArchived), errors.Is(err, services.ErrMergeBudget),
+		errors.Is(err, services.ErrCategoryDepth):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(500).JSON(fiber.Map{"error": "merge failed", "detail": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "merged", "target_id": body.TargetID, "moved": moved})
+}
+
+// findCategory memastikan kategori ada dan milik user
+func findCategory(c *fiber.Ctx, uid uint) (*models.Category, bool) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return nil, false
+	}
+	var cat models.Category
+	if err := database.DB.Where("id = ? AND user_id = ?", id, uid).First(&cat).Error; err != nil {
+		return nil, false
+	}
+	return &cat, true
+}
+
+// POST /categories/:id/archive: sembunyikan kategori (dan subkategorinya) dari pilihan.
+// Transaksi lama tetap memakai kategori ini dan tetap muncul di laporan.
+func ArchiveCategory(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	cat, ok := findCategory(c, uid)
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	if cat.ArchivedAt == nil {
+		if err := services.ArchiveCategory(database.DB, cat); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "archive failed", "detail": err.Error()})
+		}
+	}
+	return c.JSON(categoryResponse(*cat))
+}
+
+// DELETE /categories/:id/archive: pulihkan kategori arsip
+func UnarchiveCategory(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	cat, ok := findCategory(c, uid)
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	if err := services.UnarchiveCategory(database.DB, cat); err != nil {
+		if errors.Is(err, services.ErrParentArchived) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "unarchive failed", "detail": err.Error()})
+	}
+	return c.JSON(categoryResponse(*cat))
+}
@@ -0,0 +1,229 @@
+// handlers/category_templates.go
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"finance/database"
+	"finance/models"
+	"finance/services"
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// categoryTemplateNode adalah template beserta subkategorinya
+type categoryTemplateNode struct {
+	ID       uint                    `json:"id"`
+	Name     string                  `json:"name"`
+	Type     string                  `json:"type"`
+	ParentID *uint                   `json:"parent_id"`
+	Children []*categoryTemplateNode `json:"children"`
+}
+
+// templateTree menyusun template (sudah urut) menjadi pohon, dikelompokkan per locale
+func templateTree(templates []models.CategoryTemplate) map[string][]*categoryTemplateNode {
+	nodes := make(map[uint]*categoryTemplateNode, len(templates))
+	for _, t := range templates {
+		nodes[t.ID] = &categoryTemplateNode{ID: t.ID, Name: t.Name, Type: t.Type, ParentID: t.ParentID, Children: []*categoryTemplateNode{}}
+	}
+	sets := map[string][]*categoryTemplateNode{}
+	for _, t := range templates {
+		if t.ParentID != nil {
+			if parent, ok := nodes[*t.ParentID]; ok {
+				parent.Children = append(parent.Children, nodes[t.ID])
+				continue
+			}
+		}
+		sets[t.Locale] = append(sets[t.Locale], nodes[t.ID])
+	}
+	return sets
+}
+
+// GET /categories/templates?locale=: set template kategori yang tersedia
+func GetCategoryTemplates(c *fiber.Ctx) error {
+	query := database.DB.Order("locale, id")
+	if locale := c.Query("locale"); locale != "" {
+		query = query.Where("locale = ?", locale)
+	}
+	var templates []models.CategoryTemplate
+	if err := query.Find(&templates).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "query failed", "detail": err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"suggested_locale": services.PickTemplateLocale(database.DB, c.Get("Accept-Language")),
+		"templates":        templateTree(templates),
+	})
+}
+
+// POST /categories/templates/apply {"locale": "en"}
+// Tambahkan kategori dari set template; kategori yang sudah ada tidak diubah
+func ApplyCategoryTemplate(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var body struct {
+		Locale string `json:"locale"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
+	}
+	locale := body.Locale
+	if locale == "" {
+		locale = services.PickTemplateLocale(database.DB, c.Get("Accept-Language"))
+	}
+
+	var created int
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		created, err = services.ApplyCategoryTemplate(tx, uid, locale)
+		return err
+	})
+	if errors.Is(err, services.ErrUnknownTemplateLocale) {
+		return c.Status(404).JSON(fiber.Map{"error": "template not found", "locales": services.TemplateLocales(database.DB)})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "apply failed", "detail": err.Error()})
+	}
+	return c.JSON(fiber.Map{"locale": locale, "created": created})
+}
+
+// GET /admin/category-templates?locale=
+func AdminListCategoryTemplates(c *fiber.Ctx) error {
+	query := database.DB.Order("locale, id")
+	if locale := c.Query("locale"); locale != "" {
+		query = query.Where("locale = ?", locale)
+	}
+	var templates []models.CategoryTemplate
+	if err := query.Find(&templates).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "query failed", "detail": err.Error()})
+	}
+	return c.JSON(templates)
+}
+
+type categoryTemplateBody struct {
+	Locale   *string `json:"locale"` // hanya saat create
+	Name     *string `json:"name"`
+	Type     *string `json:"type"`
+	ParentID *uint   `json:"parent_id"` // 0 = template utama
+}
+
+// applyTemplateBody menyalin body ke template lalu memvalidasinya; "" bila valid
+func applyTemplateBody(t *models.CategoryTemplate, body categoryTemplateBody) string {
+	if body.Name != nil {
+		t.Name = strings.TrimSpace(*body.Name)
+	}
+	if body.Type != nil {
+		t.Type = strings.ToLower(*body.Type)
+	}
+	if body.ParentID != nil {
+		if *body.ParentID == 0 {
+			t.ParentID = nil
+		} else {
+			t.ParentID = body.ParentID
+		}
+	}
+
+	if t.Name == "" {
+		return "name cannot be empty"
+	}
+	if t.Type != "income" && t.Type != "expense" {
+		return "type must be income or expense"
+	}
+	if t.ParentID != nil {
+		if err := services.ValidateTemplateParent(database.DB, t, *t.ParentID); err != nil {
+			return err.Error()
+		}
+	}
+	if t.ID != 0 {
+		// template dengan subkategori harus tetap di tingkat utama dan bertipe sama
+		var children []models.CategoryTemplate
+		database.DB.Where("parent_id = ?", t.ID).Find(&children)
+		for _, ch := range children {
+			if t.ParentID != nil || ch.Type != t.Type {
+				return "template has subcategories"
+			}
+		}
+	}
+
+	q := database.DB.Where("locale = ? AND LOWER(name) = LOWER(?) AND type = ? AND id <> ?", t.Locale, t.Name, t.Type, t.ID)
+	if t.ParentID == nil {
+		q = q.Where("parent_id IS NULL")
+	} else {
+		q = q.Where("parent_id = ?", *t.ParentID)
+	}
+	var existing models.CategoryTemplate
+	if q.First(&existing).Error == nil {
+		return "template already exists"
+	}
+	return ""
+}
+
+// POST /admin/category-templates: tambah template (locale baru otomatis menjadi set baru)
+func AdminCreateCategoryTemplate(c *fiber.Ctx) error {
+	var body categoryTemplateBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
+	}
+	if body.Locale == nil || !services.ValidTemplateLocale(*body.Locale) {
+		return c.Status(400).JSON(fiber.Map{"error": "locale must be a language code like id, en or en-US"})
+	}
+	t := models.CategoryTemplate{Locale: *body.Locale}
+	if msg := applyTemplateBody(&t, body); msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
+	}
+	if err := database.DB.Create(&t).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "create failed", "detail": err.Error()})
+	}
+	return c.Status(201).JSON(t)
+}
+
+// PUT /admin/category-templates/:id (locale tidak bisa diubah)
+func AdminUpdateCategoryTemplate(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	var t models.CategoryTemplate
+	if err := database.DB.First(&t, id).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	var body categoryTemplateBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
+	}
+	if body.Locale != nil && *body.Locale != t.Locale {
+		return c.Status(400).JSON(fiber.Map{"error": "locale cannot be changed"})
+	}
+	if msg := applyTemplateBody(&t, body); msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
+	}
+	if err := database.DB.Save(&t).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "update failed", "detail": err.Error()})
+	}
+	return c.JSON(t)
+}
+
+// DELETE /admin/category-templates/:id (kategori user yang sudah dibuat tidak terpengaruh)
+func AdminDeleteCategoryTemplate(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	var children int64
+	database.DB.Model(&models.CategoryTemplate{}).Where("parent_id = ?", id).Count(&children)
+	if children > 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "template has subcategories"})
+	}
+	tx := database.DB.Delete(&models.CategoryTemplate{}, id)
+	if tx.Error != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "delete failed", "detail": tx.Error.Error()})
+	}
+	if tx.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	return c.JSON(fiber.Map{"message": "deleted"})
+}
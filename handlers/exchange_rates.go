@@ -0,0 +1,99 @@
+// handlers/exchange_rates.go
+package handlers
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"finance/database"
+	"finance/models"
+	"finance/money"
+	"finance/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// GET /exchange-rates?base=&quote=&date=YYYY-MM-DD
+// Tanpa date: semua kurs (terbaru dulu). Dengan date: kurs yang berlaku pada tanggal itu.
+func GetExchangeRates(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 100)
+	offset := c.QueryInt("offset", 0)
+
+	query := database.DB.Model(&models.ExchangeRate{})
+	if base := strings.ToUpper(c.Query("base")); base != "" {
+		query = query.Where("base = ?", base)
+	}
+	if quote := strings.ToUpper(c.Query("quote")); quote != "" {
+		query = query.Where("quote = ?", quote)
+	}
+	if date := c.Query("date"); date != "" {
+		parsed, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "date must be YYYY-MM-DD"})
+		}
+		// kurs terakhir pada/sebelum tanggal, satu per pasangan
+		query = query.Where(`date = (SELECT MAX(e2.date) FROM exchange_rates e2
+			WHERE e2.base = exchange_rates.base AND e2.quote = exchange_rates.quote AND e2.date <= ?)`, parsed)
+	}
+
+	var rates []models.ExchangeRate
+	if err := query.Order("date DESC, base, quote").Limit(limit).Offset(offset).Find(&rates).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "query failed", "detail": err.Error()})
+	}
+	return c.JSON(rates)
+}
+
+// POST /admin/exchange-rates: simpan satu kurs (menimpa kurs pasangan & tanggal yang sama)
+func AdminPutExchangeRate(c *fiber.Ctx) error {
+	var body struct {
+		Base  string     `json:"base"`
+		Quote string     `json:"quote"`
+		Date  string     `json:"date"` // YYYY-MM-DD
+		Rate  money.Rate `json:"rate"` // teks desimal atau angka, tidak lewat float64
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return invalidPayload(c, err)
+	}
+	r := services.ParsedRate{Base: strings.ToUpper(body.Base), Quote: strings.ToUpper(body.Quote), Rate: body.Rate}
+	if !services.ValidCurrency(r.Base) || !services.ValidCurrency(r.Quote) || r.Base == r.Quote {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid currency pair"})
+	}
+	if r.Rate.IsZero() {
+		return c.Status(400).JSON(fiber.Map{"error": "rate must be positive"})
+	}
+	parsed, err := time.Parse("2006-01-02", body.Date)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "date must be YYYY-MM-DD"})
+	}
+	r.Date = parsed
+
+	if err := services.SaveRate(database.DB, r, "manual"); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "save failed", "detail": err.Error()})
+	}
+	return c.Status(201).JSON(fiber.Map{"base": r.Base, "quote": r.Quote, "date": body.Date, "rate": r.Rate})
+}
+
+// POST /admin/exchange-rates/import: body CSV "date,base,quote,rate" (header opsional)
+func AdminImportExchangeRates(c *fiber.Ctx) error {
+	rates, err := services.ParseRatesCSV(bytes.NewReader(c.Body()))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid csv", "detail": err.Error()})
+	}
+	if len(rates) == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "no rates in file"})
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, r := range rates {
+			if err := services.SaveRate(tx, r, "import"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "import failed", "detail": err.Error()})
+	}
+	return c.JSON(fiber.Map{"imported": len(rates)})
+}
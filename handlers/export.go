@@ -0,0 +1,243 @@
+// handlers/export.go
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"finance/database"
+	"finance/models"
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GET /users/me/export: arsip zip berisi seluruh data pribadi user dalam JSON dan CSV
+func ExportMyData(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, uid).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+	}
+	var accounts []models.Account
+	var categories []models.Category
+	var transactions []models.Transaction
+	var splits []models.TransactionSplit
+	var transfers []models.Transfer
+	var budgets []models.Budget
+	var notifications []models.Notification
+	var recurring []models.RecurringTransaction
+	var bills []models.Bill
+	database.DB.Where("user_id = ?", uid).Order("id").Find(&accounts)
+	database.DB.Where("user_id = ?", uid).Order("id").Find(&categories)
+	database.DB.Where("user_id = ?", uid).Order("date").Find(&transactions)
+	database.DB.Where("user_id = ?", uid).Order("id").Find(&splits)
+	database.DB.Where("user_id = ?", uid).Order("date").Find(&transfers)
+	database.DB.Where("user_id = ?", uid).Order("id").Find(&budgets)
+	database.DB.Where("user_id = ?", uid).Order("created_at").Find(&notifications)
+	database.DB.Where("user_id = ?", uid).Order("id").Find(&recurring)
+	database.DB.Where("user_id = ?", uid).Order("id").Find(&bills)
+
+	profile := fiber.Map{
+		"id":             user.ID,
+		"name":           user.Name,
+		"email":          user.Email,
+		"email_verified": user.EmailVerifiedAt != nil,
+		"phone_number":   user.PhoneNumber,
+		"instagram":      user.Instagram,
+		"photo_url":      user.PhotoURL,
+		"created_at":     user.CreatedAt,
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct {
+		name string
+		data interface{}
+		rows [][]string
+	}{
+		{"profile", profile, [][]string{
+			{"id", "name", "email", "phone_number", "instagram", "photo_url", "created_at"},
+			{fmt.Sprint(user.ID), user.Name, user.Email, user.PhoneNumber, user.Instagram, user.PhotoURL, user.CreatedAt.Format(time.RFC3339)},
+		}},
+		{"accounts", accounts, accountRows(accounts)},
+		{"categories", categories, categoryRows(categories)},
+		{"transactions", transactions, transactionRows(transactions)},
+		{"transaction_splits", splits, splitRows(splits)},
+		{"transfers", transfers, transferRows(transfers)},
+		{"budgets", budgets, budgetRows(budgets)},
+		{"notifications", notifications, notificationRows(notifications)},
+		{"recurring_transactions", recurring, recurringRows(recurring)},
+		{"bills", bills, billRows(bills)},
+	}
+	for _, f := range files {
+		if err := writeZipJSON(zw, "json/"+f.name+".json", f.data); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "export failed"})
+		}
+		if err := writeZipCSV(zw, "csv/"+f.name+".csv", f.rows); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "export failed"})
+		}
+	}
+	if path := utils.UploadPath(user.PhotoURL); path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			w, err := zw.Create("photo/" + filepath.Base(path))
+			if err == nil {
+				w.Write(data)
+			}
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "export failed"})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="export-%d-%s.zip"`, uid, time.Now().Format("20060102")))
+	return c.Send(buf.Bytes())
+}
+
+func writeZipJSON(zw *zip.Writer, name string, data interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
+
+func writeZipCSV(zw *zip.Writer, name string, rows [][]string) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.WriteAll(rows)
+	return cw.Error()
+}
+
+func categoryRows(cats []models.Category) [][]string {
+	rows := [][]string{{"id", "name", "type", "parent_id", "archived_at", "created_at"}}
+	for _, c := range cats {
+		parentID, archivedAt := "", ""
+		if c.ParentID != nil {
+			parentID = fmt.Sprint(*c.ParentID)
+		}
+		if c.ArchivedAt != nil {
+			archivedAt = c.ArchivedAt.Format(time.RFC3339)
+		}
+		rows = append(rows, []string{fmt.Sprint(c.ID), c.Name, c.Type, parentID, archivedAt, c.CreatedAt.Format(time.RFC3339)})
+	}
+	return rows
+}
+
+func accountRows(accounts []models.Account) [][]string {
+	rows := [][]string{{"id", "name", "type", "opening_balance", "currency", "created_at"}}
+	for _, a := range accounts {
+		rows = append(rows, []string{
+			fmt.Sprint(a.ID), a.Name, a.Type, a.OpeningBalance.String(), a.Currency, a.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return rows
+}
+
+func transactionRows(trxs []models.Transaction) [][]string {
+	rows := [][]string{{"id", "category_id", "account_id", "amount", "currency", "date", "note", "created_at"}}
+	for _, t := range trxs {
+		accountID := ""
+		if t.AccountID != nil {
+			accountID = fmt.Sprint(*t.AccountID)
+		}
+		rows = append(rows, []string{
+			fmt.Sprint(t.ID), fmt.Sprint(t.CategoryID), accountID, t.Amount.String(), t.Currency,
+			t.Date.Format(time.RFC3339), t.Note, t.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return rows
+}
+
+func splitRows(splits []models.TransactionSplit) [][]string {
+	rows := [][]string{{"id", "transaction_id", "category_id", "amount", "note"}}
+	for _, s := range splits {
+		rows = append(rows, []string{
+			fmt.Sprint(s.ID), fmt.Sprint(s.TransactionID), fmt.Sprint(s.CategoryID), s.Amount.String(), s.Note,
+		})
+	}
+	return rows
+}
+
+func transferRows(transfers []models.Transfer) [][]string {
+	rows := [][]string{{"id", "from_account_id", "to_account_id", "amount", "currency", "date", "note"}}
+	for _, t := range transfers {
+		rows = append(rows, []string{
+			fmt.Sprint(t.ID), fmt.Sprint(t.FromAccountID), fmt.Sprint(t.ToAccountID), t.Amount.String(), t.Currency,
+			t.Date.Format(time.RFC3339), t.Note,
+		})
+	}
+	return rows
+}
+
+func budgetRows(budgets []models.Budget) [][]string {
+	rows := [][]string{{"id", "category_id", "limit_amount", "start_date", "end_date"}}
+	for _, b := range budgets {
+		rows = append(rows, []string{
+			fmt.Sprint(b.ID), fmt.Sprint(b.CategoryID), b.LimitAmount.String(),
+			b.StartDate.Format("2006-01-02"), b.EndDate.Format("2006-01-02"),
+		})
+	}
+	return rows
+}
+
+func notificationRows(notifs []models.Notification) [][]string {
+	rows := [][]string{{"id", "title", "message", "created_at"}}
+	for _, n := range notifs {
+		rows = append(rows, []string{fmt.Sprint(n.ID), n.Title, n.Message, n.CreatedAt.Format(time.RFC3339)})
+	}
+	return rows
+}
+
+func recurringRows(list []models.RecurringTransaction) [][]string {
+	rows := [][]string{{"id", "category_id", "account_id", "amount", "currency", "note", "frequency", "interval", "month_day", "start_date", "until", "count"}}
+	for _, r := range list {
+		accountID, until := "", ""
+		if r.AccountID != nil {
+			accountID = fmt.Sprint(*r.AccountID)
+		}
+		if r.Until != nil {
+			until = r.Until.Format("2006-01-02")
+		}
+		rows = append(rows, []string{
+			fmt.Sprint(r.ID), fmt.Sprint(r.CategoryID), accountID, r.Amount.String(), r.Currency, r.Note,
+			r.Frequency, fmt.Sprint(r.Interval), fmt.Sprint(r.MonthDay), r.StartDate.Format(time.RFC3339), until, fmt.Sprint(r.Count),
+		})
+	}
+	return rows
+}
+
+func billRows(bills []models.Bill) [][]string {
+	rows := [][]string{{"id", "payee", "amount", "currency", "category_id", "account_id", "auto_pay", "remind_days", "frequency", "interval", "month_day", "start_date", "next_due_at"}}
+	for _, b := range bills {
+		accountID, nextDue := "", ""
+		if b.AccountID != nil {
+			accountID = fmt.Sprint(*b.AccountID)
+		}
+		if b.NextDueAt != nil {
+			nextDue = b.NextDueAt.Format(time.RFC3339)
+		}
+		rows = append(rows, []string{
+			fmt.Sprint(b.ID), b.Payee, b.Amount.String(), b.Currency, fmt.Sprint(b.CategoryID), accountID,
+			fmt.Sprint(b.AutoPay), fmt.Sprint(b.RemindDays), b.Frequency, fmt.Sprint(b.Interval), fmt.Sprint(b.MonthDay),
+			b.StartDate.Format(time.RFC3339), nextDue,
+		})
+	}
+	return rows
+}
@@ -0,0 +1,207 @@
+// handlers/identity.go
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"finance/database"
+	"finance/models"
+	"finance/oidc"
+	"finance/services"
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// verifyProviderToken memverifikasi id_token untuk provider yang terdaftar di registry
+func verifyProviderToken(name, idToken string) (*oidc.Claims, *fiber.Error) {
+	provider, err := oidc.Get(name)
+	if err != nil {
+		return nil, fiber.NewError(404, "unknown provider")
+	}
+	if idToken == "" {
+		return nil, fiber.NewError(400, "id_token required")
+	}
+	claims, err := provider.Verify(idToken)
+	if err != nil {
+		return nil, fiber.NewError(401, name+" token verify failed")
+	}
+	return claims, nil
+}
+
+// socialLogin login/daftar lewat provider OIDC. Akun yang sudah ada dengan email sama
+// tidak diambil alih: user harus login dulu lalu menautkan provider dari /users/me.
+func socialLogin(c *fiber.Ctx, name string) error {
+	name = strings.ToLower(name)
+	var body struct {
+		IDToken string `json:"id_token"`
+		Device  string `json:"device"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
+	}
+	info, ferr := verifyProviderToken(name, body.IDToken)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	var user models.User
+	var identity models.UserIdentity
+	if err := database.DB.Where("provider = ? AND subject = ?", name, info.Subject).First(&identity).Error; err == nil {
+		if err := database.DB.First(&user, identity.UserID).Error; err != nil {
+			return c.Status(401).JSON(fiber.Map{"error": "account not found"})
+		}
+		return completeLogin(c, user, body.Device, fiber.Map{"id": user.ID, "email": user.Email, "name": user.Name, "photo_url": user.PhotoURL})
+	}
+
+	if !info.EmailVerified || info.Email == "" {
+		return c.Status(401).JSON(fiber.Map{"error": name + " email not verified"})
+	}
+	if err := database.DB.Where("email = ?", info.Email).First(&user).Error; err == nil {
+		var linked int64
+		database.DB.Model(&models.UserIdentity{}).Where("user_id = ?", user.ID).Count(&linked)
+		// Akun tanpa password dan tanpa identitas hanya mungkin berasal dari login Google lama
+		// (sebelum user_identities ada); provider lain tidak boleh mengambil alih lewat email
+		if user.PasswordHash != "" || linked > 0 || name != "google" {
+			return c.Status(409).JSON(fiber.Map{"error": "an account with this email already exists, log in first and link " + name + " from your profile"})
+		}
+		if err := database.DB.Create(&models.UserIdentity{UserID: user.ID, Provider: name, Subject: info.Subject, Email: info.Email}).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "login failed"})
+		}
+	} else {
+		now := time.Now()
+		user = models.User{Name: info.Name, Email: info.Email, PhotoURL: info.Picture, EmailVerifiedAt: &now}
+		locale := services.PickTemplateLocale(database.DB, info.Locale+","+c.Get("Accept-Language"))
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&models.UserIdentity{UserID: user.ID, Provider: name, Subject: info.Subject, Email: info.Email}).Error; err != nil {
+				return err
+			}
+			return seedCategories(tx, user.ID, locale)
+		})
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "register failed"})
+		}
+	}
+
+	return completeLogin(c, user, body.Device, fiber.Map{"id": user.ID, "email": user.Email, "name": user.Name, "photo_url": user.PhotoURL})
+}
+
+// POST /auth/oidc/:provider
+func OIDCLogin(c *fiber.Ctx) error {
+	return socialLogin(c, providerParam(c))
+}
+
+// providerParam: nama provider dari URL, dinormalisasi seperti kunci registry oidc
+// agar /auth/oidc/Google dan /auth/oidc/google memakai baris user_identities yang sama
+func providerParam(c *fiber.Ctx) string {
+	return strings.ToLower(c.Params("provider"))
+}
+
+// GET /users/me/identities
+func GetIdentities(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var identities []models.UserIdentity
+	if err := database.DB.Where("user_id = ?", uid).Order("created_at").Find(&identities).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "query failed", "detail": err.Error()})
+	}
+
+	results := make([]fiber.Map, 0, len(identities))
+	for _, i := range identities {
+		results = append(results, fiber.Map{
+			"provider":  i.Provider,
+			"email":     i.Email,
+			"linked_at": i.CreatedAt,
+		})
+	}
+	return c.JSON(fiber.Map{"identities": results, "available_providers": oidc.Names()})
+}
+
+// POST /users/me/identities/:provider: tautkan identitas eksternal ke akun yang sedang login
+func LinkIdentity(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	name := providerParam(c)
+	var body struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
+	}
+	info, ferr := verifyProviderToken(name, body.IDToken)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	var existing models.UserIdentity
+	if err := database.DB.Where("provider = ? AND subject = ?", name, info.Subject).First(&existing).Error; err == nil {
+		if existing.UserID == uid {
+			return c.Status(400).JSON(fiber.Map{"error": "already linked"})
+		}
+		return c.Status(409).JSON(fiber.Map{"error": "identity is linked to another account"})
+	}
+	var count int64
+	database.DB.Model(&models.UserIdentity{}).Where("user_id = ? AND provider = ?", uid, name).Count(&count)
+	if count > 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "another " + name + " account is already linked, unlink it first"})
+	}
+
+	identity := models.UserIdentity{UserID: uid, Provider: name, Subject: info.Subject, Email: info.Email}
+	if err := database.DB.Create(&identity).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "link failed", "detail": err.Error()})
+	}
+	return c.Status(201).JSON(fiber.Map{"provider": identity.Provider, "email": identity.Email, "linked_at": identity.CreatedAt})
+}
+
+var errLastLoginMethod = errors.New("last login method")
+
+// DELETE /users/me/identities/:provider: lepas tautan, selama user masih punya cara login lain
+func UnlinkIdentity(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	name := providerParam(c)
+
+	var user models.User
+	if err := database.DB.First(&user, uid).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+	}
+
+	var deleted int64
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("user_id = ? AND provider = ?", uid, name).Delete(&models.UserIdentity{})
+		if res.Error != nil {
+			return res.Error
+		}
+		deleted = res.RowsAffected
+		var remaining int64
+		if err := tx.Model(&models.UserIdentity{}).Where("user_id = ?", uid).Count(&remaining).Error; err != nil {
+			return err
+		}
+		if deleted > 0 && remaining == 0 && user.PasswordHash == "" {
+			return errLastLoginMethod
+		}
+		return nil
+	})
+	if errors.Is(err, errLastLoginMethod) {
+		return c.Status(400).JSON(fiber.Map{"error": "set a password before unlinking your last login provider"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "unlink failed", "detail": err.Error()})
+	}
+	if deleted == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	return c.JSON(fiber.Map{"message": "unlinked"})
+}
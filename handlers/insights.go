@@ -0,0 +1,151 @@
+// handlers/insights.go
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"finance/database"
+	"finance/models"
+	"finance/money"
+	"finance/services"
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GET /insights/subscriptions?include_dismissed=true
+// Langganan yang terdeteksi dari riwayat pengeluaran (catatan serupa, nominal mirip, jarak teratur)
+func GetSubscriptions(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	list, err := services.DetectSubscriptions(database.DB, uid, time.Now())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to detect subscriptions", "detail": err.Error()})
+	}
+
+	includeDismissed := c.QueryBool("include_dismissed")
+	result := []services.SuspectedSubscription{}
+	totals := map[string]money.Amount{} // biaya tahunan per mata uang
+	for _, s := range list {
+		if s.Dismissed && !includeDismissed {
+			continue
+		}
+		result = append(result, s)
+		if !s.Dismissed {
+			totals[s.Currency] += s.AnnualizedCost
+		}
+	}
+	return c.JSON(fiber.Map{"subscriptions": result, "annualized_total": totals})
+}
+
+// findSubscription mencari hasil deteksi berdasarkan :key
+func findSubscription(c *fiber.Ctx, uid uint) (*services.SuspectedSubscription, error) {
+	s, err := services.FindSubscription(database.DB, uid, c.Params("key"), time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, c.Status(404).JSON(fiber.Map{"error": "subscription not found"})
+	}
+	if err != nil {
+		return nil, c.Status(500).JSON(fiber.Map{"error": "failed to detect subscriptions", "detail": err.Error()})
+	}
+	return s, nil
+}
+
+// POST /insights/subscriptions/:key/confirm
+// Jadikan template recurring mulai tanggal perkiraan berikutnya. Body opsional sama seperti
+// PUT /recurring/:id untuk mengubah usulan (mis. amount atau account_id).
+// Transaksi lama yang cocok ditautkan ke template agar tidak terdeteksi lagi.
+func ConfirmSubscription(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	s, resp := findSubscription(c, uid)
+	if s == nil {
+		return resp
+	}
+	var body recurringBody
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return invalidPayload(c, err)
+		}
+	}
+
+	r := models.RecurringTransaction{
+		UserID:     uid,
+		CategoryID: s.CategoryID,
+		AccountID:  s.AccountID,
+		Amount:     s.LastAmount, // harga terakhir, bukan rata-rata
+		Currency:   s.Currency,
+		Note:       s.Name,
+		Recurrence: s.Recurrence,
+	}
+	if msg := applyRecurringBody(uid, &r, body); msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
+	}
+	r.NextRunAt = services.NextRunFor(&r.Recurrence, 0)
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&r).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.Transaction{}).
+			Where("id IN ? AND user_id = ? AND recurring_id IS NULL", s.TransactionIDs, uid).
+			Update("recurring_id", r.ID).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND key = ?", uid, s.Key).Delete(&models.SubscriptionDismissal{}).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "confirm failed", "detail": err.Error()})
+	}
+
+	created, err := services.MaterializeDue(database.DB, r, time.Now())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "confirm failed", "detail": err.Error()})
+	}
+	database.DB.First(&r, r.ID)
+	return c.Status(201).JSON(fiber.Map{
+		"recurring":           r,
+		"linked_transactions": len(s.TransactionIDs),
+		"created_occurrences": created,
+	})
+}
+
+// POST /insights/subscriptions/:key/dismiss: sembunyikan hasil deteksi (bukan langganan)
+func DismissSubscription(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	s, resp := findSubscription(c, uid)
+	if s == nil {
+		return resp
+	}
+	d := models.SubscriptionDismissal{UserID: uid, Key: s.Key, Pattern: s.Pattern, DismissedAt: time.Now()}
+	if err := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&d).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "dismiss failed", "detail": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "subscription dismissed", "key": s.Key})
+}
+
+// DELETE /insights/subscriptions/:key/dismiss: tampilkan lagi hasil deteksi yang disembunyikan
+func UndismissSubscription(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	res := database.DB.Where("user_id = ? AND key = ?", uid, c.Params("key")).Delete(&models.SubscriptionDismissal{})
+	if res.Error != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "restore failed", "detail": res.Error.Error()})
+	}
+	if res.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "dismissal not found"})
+	}
+	return c.JSON(fiber.Map{"message": "subscription restored"})
+}
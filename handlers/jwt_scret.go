@@ -1,6 +0,0 @@
-// handlers/jwt_secret.go
-package handlers
-
-var jwtSecret = "akugantengbanget"
-
-func JwtSecret() string { return jwtSecret }
@@ -0,0 +1,88 @@
+// handlers/login_guard.go
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"finance/database"
+	"finance/models"
+	"finance/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(c *fiber.Ctx) string {
+	return "ip:" + c.IP()
+}
+
+// loginThrottled mengirim 429 bila akun atau IP sedang dalam masa backoff/lockout
+func loginThrottled(c *fiber.Ctx, email string) (bool, error) {
+	wait := services.Guard.Blocked(services.AccountPolicy, accountKey(email))
+	if w := services.Guard.Blocked(services.IPPolicy, ipKey(c)); w > wait {
+		wait = w
+	}
+	if wait <= 0 {
+		return false, nil
+	}
+	c.Set(fiber.HeaderRetryAfter, fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+	return true, c.Status(429).JSON(fiber.Map{"error": "too many failed attempts, try again later"})
+}
+
+// recordLoginFailure mencatat kegagalan untuk akun dan IP; user (boleh nil) diberi notifikasi saat akunnya terkunci
+func recordLoginFailure(c *fiber.Ctx, email string, user *models.User) {
+	if _, _, err := services.Guard.Fail(services.IPPolicy, ipKey(c)); err != nil {
+		log.Println("Gagal catat login attempt:", err)
+	}
+	state, locked, err := services.Guard.Fail(services.AccountPolicy, accountKey(email))
+	if err != nil {
+		log.Println("Gagal catat login attempt:", err)
+		return
+	}
+	if locked && user != nil {
+		notif := models.Notification{
+			UserID:    user.ID,
+			Title:     "Security Alert",
+			Message:   fmt.Sprintf("Akun Anda dikunci sementara hingga %s karena terlalu banyak percobaan login gagal.", state.LockedUntil.Format("02 Jan 2006 15:04")),
+			CreatedAt: time.Now(),
+		}
+		if err := database.DB.Create(&notif).Error; err != nil {
+			log.Println("Gagal simpan notifikasi:", err)
+		}
+	}
+}
+
+func recordLoginSuccess(email string) {
+	if err := services.Guard.Succeed(accountKey(email)); err != nil {
+		log.Println("Gagal reset login attempt:", err)
+	}
+}
+
+// GET /admin/login-attempts: counter percobaan login gagal dan lockout aktif
+func GetLoginAttempts(c *fiber.Ctx) error {
+	list, err := services.Guard.Store.List()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "query failed", "detail": err.Error()})
+	}
+	now := time.Now()
+	locked := 0
+	failures := 0
+	for _, s := range list {
+		failures += s.Failures
+		if now.Before(s.LockedUntil) {
+			locked++
+		}
+	}
+	return c.JSON(fiber.Map{
+		"total_failures":  failures,
+		"active_lockouts": locked,
+		"attempts":        list,
+	})
+}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"fmt"
 	"time"
 
 	"finance/database"
@@ -11,20 +10,6 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-// Tambah notifikasi otomatis (misalnya dipanggil dari budget handler)
-func AddBudgetNotification(userID uint, category string) error {
-	notif := models.Notification{
-		UserID:    userID,
-		Title:     "Budget Alert",
-		Message:   fmt.Sprintf("Kategori %s sudah melebihi batas!", category),
-		CreatedAt: time.Now(),
-	}
-	if err := database.DB.Create(&notif).Error; err != nil {
-		return err
-	}
-	return nil
-}
-
 // Endpoint: buat notifikasi manual (POST /notifications)
 func CreateNotification(c *fiber.Ctx) error {
 	uid, err := utils.GetUserID(c)
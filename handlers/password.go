@@ -0,0 +1,127 @@
+// handlers/password.go
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"finance/database"
+	"finance/mailer"
+	"finance/models"
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const passwordResetTTL = time.Hour
+
+// appLink membuat URL ke frontend (APP_BASE_URL) untuk dicantumkan di email
+func appLink(path, token string) string {
+	base := os.Getenv("APP_BASE_URL")
+	if base == "" {
+		return ""
+	}
+	return base + path + "?token=" + token
+}
+
+// POST /auth/password/forgot: kirim token reset ke email user.
+// Respons selalu sama agar tidak membocorkan email mana yang terdaftar.
+func ForgotPassword(c *fiber.Ctx) error {
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Email == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "email required"})
+	}
+	resp := fiber.Map{"message": "if the email is registered, a reset link has been sent"}
+
+	var user models.User
+	if err := database.DB.Where("email = ?", body.Email).First(&user).Error; err != nil {
+		return c.JSON(resp)
+	}
+	if err := sendPasswordReset(user); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "create reset token failed"})
+	}
+	return c.JSON(resp)
+}
+
+// sendPasswordReset membuat token reset baru (token lama hangus) dan mengirimkannya ke email user
+func sendPasswordReset(user models.User) error {
+	raw, err := utils.RandomToken()
+	if err != nil {
+		return err
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Hanya token terbaru yang berlaku
+		if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: utils.HashToken(raw),
+			ExpiresAt: time.Now().Add(passwordResetTTL),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("Halo %s,\n\nGunakan token berikut untuk mengatur ulang password Anda (berlaku 1 jam):\n\n%s\n", user.Name, raw)
+	if link := appLink("/reset-password", raw); link != "" {
+		msg += "\nAtau buka: " + link + "\n"
+	}
+	msg += "\nAbaikan email ini jika Anda tidak meminta reset password.\n"
+	if err := mailer.Send(mailer.Message{To: user.Email, Subject: "Reset password", Body: msg}); err != nil {
+		log.Println("Gagal kirim email reset password:", err)
+	}
+	return nil
+}
+
+// POST /auth/password/reset: set password baru memakai token dari email
+func ResetPassword(c *fiber.Ctx) error {
+	var body struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Token == "" || body.NewPassword == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "token/new_password required"})
+	}
+
+	var rt models.PasswordResetToken
+	if err := database.DB.Where("token_hash = ?", utils.HashToken(body.Token)).First(&rt).Error; err != nil ||
+		rt.UsedAt != nil || time.Now().After(rt.ExpiresAt) {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid or expired token"})
+	}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte(body.NewPassword), 12)
+	used := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", rt.ID).
+			Update("used_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			used = true
+			return nil
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", rt.UserID).
+			Updates(map[string]interface{}{"password_hash": string(hash), "password_reset_required": false}).Error; err != nil {
+			return err
+		}
+		// Semua login lama tidak berlaku lagi setelah reset
+		return revokeUserSessions(tx, rt.UserID, 0)
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "reset failed"})
+	}
+	if used {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid or expired token"})
+	}
+	return c.JSON(fiber.Map{"message": "password has been reset"})
+}
@@ -0,0 +1,419 @@
+// handlers/recurring.go
+package handlers
+
+import (
+	"time"
+
+	"finance/database"
+	"finance/models"
+	"finance/money"
+	"finance/services"
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recurringBody dipakai untuk create (field wajib dicek) dan update (nil = tidak diubah)
+type recurringBody struct {
+	CategoryID *uint         `json:"category_id"`
+	AccountID  *uint         `json:"account_id"` // 0 = tanpa akun
+	Amount     *money.Amount `json:"amount"`
+	Currency   *string       `json:"currency"`
+	Note       *string       `json:"note"`
+	Frequency  *string       `json:"frequency"` // daily, weekly, monthly
+	Interval   *int          `json:"interval"`
+	MonthDay   *int          `json:"month_day"`  // monthly: 1..31, -1 = akhir bulan
+	StartDate  *string       `json:"start_date"` // RFC3339 atau YYYY-MM-DD
+	Until      *string       `json:"until"`      // YYYY-MM-DD, "" = tanpa tanggal akhir
+	Count      *int          `json:"count"`      // 0 = tanpa batas
+}
+
+// parseRecurringDate menerima RFC3339 atau YYYY-MM-DD
+func parseRecurringDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// applyRecurringBody menyalin body ke template lalu memvalidasi kategori, akun, mata uang
+// (lewat services.BuildTransaction, sama seperti transaksi biasa) dan aturan jadwal.
+// Mengembalikan pesan error untuk response 400, "" bila valid.
+func applyRecurringBody(uid uint, r *models.RecurringTransaction, body recurringBody) string {
+	if body.CategoryID != nil {
+		r.CategoryID = *body.CategoryID
+	}
+	if body.AccountID != nil {
+		if *body.AccountID == 0 {
+			r.AccountID = nil
+		} else {
+			r.AccountID = body.AccountID
+		}
+		if body.Currency == nil {
+			r.Currency = "" // ikut mata uang akun yang baru
+		}
+	}
+	if body.Currency != nil {
+		r.Currency = *body.Currency
+	}
+	if body.Amount != nil {
+		r.Amount = *body.Amount
+	}
+	if body.Note != nil {
+		r.Note = *body.Note
+	}
+	if body.Frequency != nil {
+		r.Frequency = *body.Frequency
+	}
+	if body.Interval != nil {
+		r.Interval = *body.Interval
+	}
+	if body.MonthDay != nil {
+		r.MonthDay = *body.MonthDay
+	}
+	if body.StartDate != nil {
+		sd, err := parseRecurringDate(*body.StartDate)
+		if err != nil {
+			return "invalid start_date"
+		}
+		r.StartDate = sd
+	}
+	if body.Until != nil {
+		if *body.Until == "" {
+			r.Until = nil
+		} else {
+			until, err := time.Parse("2006-01-02", *body.Until)
+			if err != nil {
+				return "invalid until"
+			}
+			r.Until = &until
+		}
+	}
+	if body.Count != nil {
+		r.Count = *body.Count
+	}
+
+	if msg := checkAmount("amount", r.Amount); msg != "" {
+		return msg
+	}
+	if r.Note == "" {
+		return "note cannot be empty"
+	}
+	trx, _, err := services.BuildTransaction(database.DB, uid, services.NewTransaction{
+		CategoryID: r.CategoryID,
+		AccountID:  r.AccountID,
+		Amount:     r.Amount,
+		Note:       r.Note,
+		Currency:   r.Currency,
+	})
+	if err != nil {
+		return err.Error()
+	}
+	r.Currency = trx.Currency
+	if err := services.ValidateRecurrence(&r.Recurrence); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// findRecurring memastikan template ada dan milik user
+func findRecurring(c *fiber.Ctx, uid uint) (*models.RecurringTransaction, bool) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return nil, false
+	}
+	var r models.RecurringTransaction
+	if err := database.DB.Where("id = ? AND user_id = ?", id, uid).First(&r).Error; err != nil {
+		return nil, false
+	}
+	return &r, true
+}
+
+// POST /recurring: buat template transaksi berulang.
+// start_date di masa lalu berarti kemunculan yang terlewat langsung dibuat (catch-up).
+func CreateRecurring(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var body recurringBody
+	if err := c.BodyParser(&body); err != nil {
+		return invalidPayload(c, err)
+	}
+	if body.CategoryID == nil || body.Amount == nil || body.Frequency == nil || body.StartDate == nil {
+		return c.Status(400).JSON(fiber.Map{"error": "category_id, amount, frequency and start_date are required"})
+	}
+
+	r := models.RecurringTransaction{UserID: uid, Recurrence: models.Recurrence{Interval: 1}}
+	if msg := applyRecurringBody(uid, &r, body); msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
+	}
+	r.NextRunAt = services.NextRunFor(&r.Recurrence, 0)
+	if err := database.DB.Create(&r).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "create failed", "detail": err.Error()})
+	}
+
+	created, err := services.MaterializeDue(database.DB, r, time.Now())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "create failed", "detail": err.Error()})
+	}
+	database.DB.First(&r, r.ID)
+	return c.Status(201).JSON(fiber.Map{"recurring": r, "created_occurrences": created})
+}
+
+// POST /recurring/preview: hitung jadwal tanpa menyimpan template
+func PreviewRecurringRule(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var body recurringBody
+	if err := c.BodyParser(&body); err != nil {
+		return invalidPayload(c, err)
+	}
+	if body.CategoryID == nil || body.Amount == nil || body.Frequency == nil || body.StartDate == nil {
+		return c.Status(400).JSON(fiber.Map{"error": "category_id, amount, frequency and start_date are required"})
+	}
+	r := models.RecurringTransaction{UserID: uid, Recurrence: models.Recurrence{Interval: 1}}
+	if msg := applyRecurringBody(uid, &r, body); msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
+	}
+	return c.JSON(services.PreviewRecurring(database.DB, &r, previewCount(c)))
+}
+
+func previewCount(c *fiber.Ctx) int {
+	n := c.QueryInt("count", 12)
+	if n < 1 {
+		n = 1
+	}
+	if n > 100 {
+		n = 100
+	}
+	return n
+}
+
+// GET /recurring
+func GetRecurring(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var list []models.RecurringTransaction
+	if err := database.DB.Where("user_id = ?", uid).Order("id").Find(&list).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "query failed", "detail": err.Error()})
+	}
+	return c.JSON(list)
+}
+
+// GET /recurring/:id
+func GetRecurringDetail(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	r, ok := findRecurring(c, uid)
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	var occurrences []models.RecurringOccurrence
+	database.DB.Where("recurring_id = ?", r.ID).Order("date").Find(&occurrences)
+	return c.JSON(fiber.Map{"recurring": r, "occurrences": occurrences})
+}
+
+// GET /recurring/:id/preview?count=12: kemunculan berikutnya beserta skip/edit per tanggal
+func PreviewRecurring(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	r, ok := findRecurring(c, uid)
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	return c.JSON(services.PreviewRecurring(database.DB, r, previewCount(c)))
+}
+
+// PUT /recurring/:id: ubah seluruh seri. Berlaku untuk kemunculan yang belum dibuat;
+// transaksi yang sudah dibuat tidak berubah. Bila aturan jadwal berubah, perubahan per
+// tanggal yang belum jatuh tempo dihapus dan penomoran dilanjutkan setelah kemunculan terakhir.
+func UpdateRecurring(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var body recurringBody
+	if err := c.BodyParser(&body); err != nil {
+		return invalidPayload(c, err)
+	}
+	r, ok := findRecurring(c, uid)
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+
+	var msg string
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// kunci template agar worker tidak membuat kemunculan di tengah perubahan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(r, r.ID).Error; err != nil {
+			return err
+		}
+		old := *r
+		if msg = applyRecurringBody(uid, r, body); msg != "" {
+			return nil
+		}
+		ruleChanged := r.Frequency != old.Frequency || r.Interval != old.Interval ||
+			r.MonthDay != old.MonthDay || !r.StartDate.Equal(old.StartDate)
+		if ruleChanged {
+			del := tx.Where("recurring_id = ? AND status IN ?", r.ID, []string{models.OccurrencePending, models.OccurrenceSkipped})
+			if old.NextIndex > 0 {
+				lastDone := services.OccurrenceDate(&old.Recurrence, old.NextIndex-1)
+				r.NextIndex = services.FirstIndexAfter(&r.Recurrence, lastDone)
+				del = del.Where("date > ?", lastDone.Format("2006-01-02"))
+			}
+			if err := del.Delete(&models.RecurringOccurrence{}).Error; err != nil {
+				return err
+			}
+		}
+		r.NextRunAt = services.NextRunFor(&r.Recurrence, r.NextIndex)
+		return tx.Save(r).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "update failed", "detail": err.Error()})
+	}
+	if msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
+	}
+
+	if _, err := services.MaterializeDue(database.DB, *r, time.Now()); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "update failed", "detail": err.Error()})
+	}
+	database.DB.First(r, r.ID)
+	return c.JSON(r)
+}
+
+// DELETE /recurring/:id: hentikan seri; transaksi yang sudah dibuat tetap ada
+func DeleteRecurring(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	r, ok := findRecurring(c, uid)
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Transaction{}).Where("recurring_id = ?", r.ID).Update("recurring_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("recurring_id = ?", r.ID).Delete(&models.RecurringOccurrence{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(r).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "delete failed", "detail": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "deleted"})
+}
+
+// findOccurrence mengecek bahwa :date (YYYY-MM-DD) adalah kemunculan seri yang belum diproses
+func findOccurrence(c *fiber.Ctx, r *models.RecurringTransaction) (time.Time, *fiber.Error) {
+	day, err := time.Parse("2006-01-02", c.Params("date"))
+	if err != nil {
+		return day, fiber.NewError(400, "date must be YYYY-MM-DD")
+	}
+	n, err := services.OccurrenceIndex(&r.Recurrence, day)
+	if err != nil {
+		return day, fiber.NewError(404, err.Error())
+	}
+	if n < r.NextIndex {
+		return day, fiber.NewError(409, "occurrence already processed; edit the transaction instead")
+	}
+	return day, nil
+}
+
+// PUT /recurring/:id/occurrences/:date: skip atau ubah satu kemunculan saja.
+// Body: {"skip": true} atau {"amount": .., "note": .., "category_id": ..}
+func UpdateOccurrence(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var body struct {
+		Skip       bool          `json:"skip"`
+		CategoryID *uint         `json:"category_id"`
+		Amount     *money.Amount `json:"amount"`
+		Note       *string       `json:"note"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return invalidPayload(c, err)
+	}
+	r, ok := findRecurring(c, uid)
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	day, ferr := findOccurrence(c, r)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	occ := models.RecurringOccurrence{RecurringID: r.ID, UserID: uid, Date: day}
+	database.DB.Where("recurring_id = ? AND date = ?", r.ID, day.Format("2006-01-02")).First(&occ)
+	if body.Skip {
+		occ.Status = models.OccurrenceSkipped
+	} else {
+		occ.Status = models.OccurrencePending
+		if body.CategoryID != nil {
+			var cat models.Category
+			if err := database.DB.Where("id = ? AND user_id = ?", *body.CategoryID, uid).First(&cat).Error; err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "invalid category"})
+			}
+			if cat.ArchivedAt != nil {
+				return c.Status(400).JSON(fiber.Map{"error": services.ErrArchivedCategory.Error()})
+			}
+			occ.CategoryID = body.CategoryID
+		}
+		if body.Amount != nil {
+			if msg := checkAmount("amount", *body.Amount); msg != "" {
+				return c.Status(400).JSON(fiber.Map{"error": msg})
+			}
+			occ.Amount = body.Amount
+		}
+		if body.Note != nil {
+			if *body.Note == "" {
+				return c.Status(400).JSON(fiber.Map{"error": "note cannot be empty"})
+			}
+			occ.Note = body.Note
+		}
+	}
+	if err := database.DB.Save(&occ).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "update failed", "detail": err.Error()})
+	}
+	return c.JSON(occ)
+}
+
+// DELETE /recurring/:id/occurrences/:date: batalkan skip/perubahan, kembali ikut template
+func ResetOccurrence(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	r, ok := findRecurring(c, uid)
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	day, ferr := findOccurrence(c, r)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	res := database.DB.Where("recurring_id = ? AND date = ? AND status IN ?", r.ID, day.Format("2006-01-02"),
+		[]string{models.OccurrencePending, models.OccurrenceSkipped}).Delete(&models.RecurringOccurrence{})
+	if res.Error != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "delete failed", "detail": res.Error.Error()})
+	}
+	if res.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "occurrence has no changes"})
+	}
+	return c.JSON(fiber.Map{"message": "reset"})
+}
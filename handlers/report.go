@@ -2,12 +2,29 @@
 package handlers
 
 import (
+	"strconv"
+
 	"finance/database"
+	"finance/models"
+	"finance/money"
+	"finance/services"
 	"finance/utils"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// reportCurrency melengkapi kurs yang dibutuhkan laporan dan mengembalikan mata uang dasar user.
+// Jumlah transaksi yang tidak bisa dikonversi (kurs tidak ada) dikirim di header.
+func reportCurrency(c *fiber.Ctx, uid uint) (string, int64) {
+	base := services.BaseCurrency(database.DB, uid)
+	unconverted := services.EnsureRates(database.DB, uid, base)
+	c.Set("X-Base-Currency", base)
+	if unconverted > 0 {
+		c.Set("X-Unconverted-Transactions", strconv.FormatInt(unconverted, 10))
+	}
+	return base, unconverted
+}
+
 // FR-14..FR-20
 func GetSummary(c *fiber.Ctx) error {
 	uid, err := utils.GetUserID(c)
@@ -15,27 +32,54 @@ func GetSummary(c *fiber.Ctx) error {
 		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
 	}
 
-	var totalIncome, totalExpense float64
+	var totalIncome, totalExpense money.Amount
+	base, unconverted := reportCurrency(c, uid)
+	amount := services.ConvertSQL("t.amount", "t.currency", "t.date", base)
+
+	// Leg transfer (category_id 0) otomatis tidak ikut karena JOIN ke kategori.
+	// filter opsional per akun
+	filter := ""
+	args := []interface{}{uid}
+	var account *models.Account
+	if accountID := c.QueryInt("account_id", 0); accountID > 0 {
+		acc, ok := findAccount(uid, uint(accountID))
+		if !ok {
+			return c.Status(404).JSON(fiber.Map{"error": "account not found"})
+		}
+		account = acc
+		filter = " AND t.account_id = ?"
+		args = append(args, acc.ID)
+	}
 
 	database.DB.Raw(`
-        SELECT COALESCE(SUM(t.amount),0)
+        SELECT COALESCE(SUM(`+amount+`),0)
         FROM transactions t
         JOIN categories c ON t.category_id = c.id
-        WHERE t.user_id = ? AND c.type = 'income'
-    `, uid).Scan(&totalIncome)
+        WHERE t.user_id = ? AND c.type = 'income'`+filter, args...).Scan(&totalIncome)
 
 	database.DB.Raw(`
-        SELECT COALESCE(SUM(t.amount),0)
+        SELECT COALESCE(SUM(`+amount+`),0)
         FROM transactions t
         JOIN categories c ON t.category_id = c.id
-        WHERE t.user_id = ? AND c.type = 'expense'
-    `, uid).Scan(&totalExpense)
-
-	return c.JSON(fiber.Map{
-		"total_income":  totalIncome,
-		"total_expense": totalExpense,
-		"saldo":         totalIncome - totalExpense,
-	})
+        WHERE t.user_id = ? AND c.type = 'expense'`+filter, args...).Scan(&totalExpense)
+
+	resp := fiber.Map{
+		"total_income":             totalIncome,
+		"total_expense":            totalExpense,
+		"saldo":                    totalIncome - totalExpense,
+		"base_currency":            base,
+		"unconverted_transactions": unconverted,
+	}
+	if account != nil {
+		// saldo akun (dalam mata uang akun) juga memperhitungkan transfer masuk/keluar
+		accounts := []models.Account{*account}
+		services.FillBalances(database.DB, uid, accounts)
+		resp["account_id"] = account.ID
+		resp["account_currency"] = account.Currency
+		resp["opening_balance"] = account.OpeningBalance
+		resp["balance"] = accounts[0].Balance
+	}
+	return c.JSON(resp)
 }
 
 func GetMonthlySummary(c *fiber.Ctx) error {
@@ -45,44 +89,130 @@ func GetMonthlySummary(c *fiber.Ctx) error {
 	}
 
 	var results []struct {
-		Month        string  `json:"month"`
-		TotalIncome  float64 `json:"total_income"`
-		TotalExpense float64 `json:"total_expense"`
+		Month        string               `json:"month"`
+		TotalIncome  money.Amount         `json:"total_income"`
+		TotalExpense money.Amount         `json:"total_expense"`
+		Categories   []monthlyCategoryRow `json:"categories" gorm:"-"`
 	}
 
-	// ✅ gunakan DATE_FORMAT untuk MySQL
+	base, _ := reportCurrency(c, uid)
+	amount := services.ConvertSQL("t.amount", "t.currency", "t.date", base)
+
+	// Postgres: TO_CHAR (DATE_FORMAT hanya ada di MySQL)
 	database.DB.Raw(`
-        SELECT DATE_FORMAT(t.date, '%Y-%m') AS month,
-               SUM(CASE WHEN c.type='income' THEN t.amount ELSE 0 END) AS total_income,
-               SUM(CASE WHEN c.type='expense' THEN t.amount ELSE 0 END) AS total_expense
+        SELECT TO_CHAR(t.date, 'YYYY-MM') AS month,
+               COALESCE(SUM(CASE WHEN c.type='income' THEN `+amount+` ELSE 0 END),0) AS total_income,
+               COALESCE(SUM(CASE WHEN c.type='expense' THEN `+amount+` ELSE 0 END),0) AS total_expense
         FROM transactions t
         JOIN categories c ON t.category_id = c.id
         WHERE t.user_id = ?
-        GROUP BY DATE_FORMAT(t.date, '%Y-%m')
+        GROUP BY TO_CHAR(t.date, 'YYYY-MM')
         ORDER BY month
     `, uid).Scan(&results)
 
+	// rincian per kategori utama: total subkategori digabung ke induknya
+	var lines []struct {
+		Month      string
+		CategoryID uint
+		Total      money.Amount
+	}
+	database.DB.Raw(`
+        SELECT TO_CHAR(tl.date, 'YYYY-MM') AS month, tl.category_id,
+               COALESCE(SUM(`+services.ConvertSQL("tl.amount", "tl.currency", "tl.date", base)+`),0) AS total
+        FROM `+services.CategoryLinesSQL+` tl
+        JOIN categories c ON tl.category_id = c.id
+        WHERE tl.user_id = ?
+        GROUP BY TO_CHAR(tl.date, 'YYYY-MM'), tl.category_id
+    `, uid).Scan(&lines)
+
+	var cats []models.Category
+	database.DB.Where("user_id = ?", uid).Order("name").Find(&cats)
+	roots := services.CategoryRoots(cats)
+	perMonth := map[string]map[uint]money.Amount{}
+	for _, l := range lines {
+		if perMonth[l.Month] == nil {
+			perMonth[l.Month] = map[uint]money.Amount{}
+		}
+		perMonth[l.Month][roots[l.CategoryID]] += l.Total
+	}
+	for i := range results {
+		results[i].Categories = []monthlyCategoryRow{}
+		totals := perMonth[results[i].Month]
+		for _, cat := range cats {
+			if total, ok := totals[cat.ID]; ok && roots[cat.ID] == cat.ID {
+				results[i].Categories = append(results[i].Categories, monthlyCategoryRow{
+					CategoryID: cat.ID, CategoryName: cat.Name, Type: cat.Type, Total: total,
+				})
+			}
+		}
+	}
+
 	return c.JSON(results)
 }
 
+type monthlyCategoryRow struct {
+	CategoryID   uint         `json:"category_id"`
+	CategoryName string       `json:"category_name"`
+	Type         string       `json:"type"`
+	Total        money.Amount `json:"total"`
+}
+
+// categoryExpense adalah total pengeluaran kategori termasuk semua subkategorinya
+type categoryExpense struct {
+	CategoryID   uint               `json:"category_id"`
+	CategoryName string             `json:"category_name"`
+	TotalExpense money.Amount       `json:"total_expense"`
+	Children     []*categoryExpense `json:"children"`
+}
+
+// expenseTree mengubah pohon kategori menjadi pohon total; kategori tanpa pengeluaran dilewati
+func expenseTree(nodes []*services.CategoryNode, rolled map[uint]money.Amount) []*categoryExpense {
+	out := []*categoryExpense{}
+	for _, n := range nodes {
+		total, ok := rolled[n.ID]
+		if !ok {
+			continue
+		}
+		out = append(out, &categoryExpense{
+			CategoryID:   n.ID,
+			CategoryName: n.Name,
+			TotalExpense: total,
+			Children:     expenseTree(n.Children, rolled),
+		})
+	}
+	return out
+}
+
 func GetExpenseByCategory(c *fiber.Ctx) error {
 	uid, err := utils.GetUserID(c)
 	if err != nil {
 		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
 	}
 
-	var results []struct {
-		CategoryName string  `json:"category_name"`
-		TotalExpense float64 `json:"total_expense"`
+	var rows []struct {
+		CategoryID uint
+		Total      money.Amount
 	}
 
+	base, _ := reportCurrency(c, uid)
+
+	// transaksi split dihitung per baris kategorinya
 	database.DB.Raw(`
-        SELECT c.name AS category_name, COALESCE(SUM(t.amount),0) AS total_expense
-        FROM transactions t
-        JOIN categories c ON t.category_id = c.id
-        WHERE t.user_id = ? AND c.type = 'expense'
-        GROUP BY c.name
-    `, uid).Scan(&results)
+        SELECT tl.category_id, COALESCE(SUM(`+services.ConvertSQL("tl.amount", "tl.currency", "tl.date", base)+`),0) AS total
+        FROM `+services.CategoryLinesSQL+` tl
+        JOIN categories c ON tl.category_id = c.id
+        WHERE tl.user_id = ? AND c.type = 'expense'
+        GROUP BY tl.category_id
+    `, uid).Scan(&rows)
 
-	return c.JSON(results)
+	// kategori utama mencakup total subkategorinya; rinciannya ada di children
+	var cats []models.Category
+	database.DB.Where("user_id = ? AND type = 'expense'", uid).Order("name").Find(&cats)
+	direct := make(map[uint]money.Amount, len(rows))
+	for _, r := range rows {
+		direct[r.CategoryID] = r.Total
+	}
+	rolled := services.RollUpCategoryTotals(cats, direct)
+
+	return c.JSON(expenseTree(services.BuildCategoryTree(cats), rolled))
 }
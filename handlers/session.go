@@ -0,0 +1,233 @@
+// handlers/session.go
+package handlers
+
+import (
+	"time"
+
+	"finance/database"
+	"finance/jwtkeys"
+	"finance/models"
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// signAccessToken membuat access token berumur pendek yang terikat ke sebuah sesi
+func signAccessToken(user models.User, sessionID uint) (string, error) {
+	return jwtkeys.Keys.Sign(jwt.MapClaims{
+		"user_id": user.ID,
+		"sid":     sessionID,
+		"role":    user.Role,
+		"exp":     time.Now().Add(accessTokenTTL).Unix(),
+	})
+}
+
+// issueRefreshToken membuat refresh token baru untuk sesi dan menyimpan hash-nya
+func issueRefreshToken(tx *gorm.DB, sess *models.Session) (string, error) {
+	raw, err := utils.RandomToken()
+	if err != nil {
+		return "", err
+	}
+	expires := time.Now().Add(refreshTokenTTL)
+	rt := models.RefreshToken{
+		SessionID: sess.ID,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: expires,
+	}
+	if err := tx.Create(&rt).Error; err != nil {
+		return "", err
+	}
+	if err := tx.Model(sess).Updates(map[string]interface{}{"expires_at": expires, "last_seen_at": time.Now()}).Error; err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// startSession membuat sesi baru untuk user dan mengembalikan pasangan token.
+// device opsional, dikirim client (mis. "Pixel 7" / "Chrome di laptop")
+func startSession(c *fiber.Ctx, user models.User, device string) (fiber.Map, error) {
+	var access, refresh string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		sess := models.Session{
+			UserID:     user.ID,
+			Device:     truncate(device, 100),
+			UserAgent:  truncate(c.Get(fiber.HeaderUserAgent), 255),
+			IP:         c.IP(),
+			LastSeenAt: time.Now(),
+			ExpiresAt:  time.Now().Add(refreshTokenTTL),
+		}
+		if err := tx.Create(&sess).Error; err != nil {
+			return err
+		}
+		var err error
+		if refresh, err = issueRefreshToken(tx, &sess); err != nil {
+			return err
+		}
+		access, err = signAccessToken(user, sess.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tokenPair(access, refresh), nil
+}
+
+func tokenPair(access, refresh string) fiber.Map {
+	return fiber.Map{
+		"token":         access,
+		"refresh_token": refresh,
+		"expires_in":    int(accessTokenTTL.Seconds()),
+	}
+}
+
+// revokeSession menandai sesi (beserta seluruh refresh token-nya) sebagai tidak berlaku
+func revokeSession(tx *gorm.DB, sessionID uint) error {
+	return tx.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// revokeUserSessions mencabut semua sesi aktif user kecuali exceptID (0 = cabut semua)
+func revokeUserSessions(tx *gorm.DB, userID, exceptID uint) error {
+	return tx.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, exceptID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// POST /auth/refresh: tukar refresh token dengan pasangan token baru (rotasi)
+func RefreshToken(c *fiber.Ctx) error {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.RefreshToken == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "refresh_token required"})
+	}
+
+	var rt models.RefreshToken
+	if err := database.DB.Where("token_hash = ?", utils.HashToken(body.RefreshToken)).First(&rt).Error; err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "invalid refresh token"})
+	}
+	var sess models.Session
+	if err := database.DB.First(&sess, rt.SessionID).Error; err != nil || sess.RevokedAt != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "session revoked"})
+	}
+
+	// Token yang sudah pernah dipakai berarti bocor: cabut seluruh sesi
+	if rt.UsedAt != nil {
+		revokeSession(database.DB, sess.ID)
+		return c.Status(401).JSON(fiber.Map{"error": "refresh token reuse detected"})
+	}
+	if time.Now().After(rt.ExpiresAt) {
+		return c.Status(401).JSON(fiber.Map{"error": "refresh token expired"})
+	}
+	// Role dibaca ulang agar perubahan role ikut masuk ke access token berikutnya
+	var user models.User
+	if err := database.DB.First(&user, sess.UserID).Error; err != nil || user.DisabledAt != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "account disabled"})
+	}
+
+	var access, refresh string
+	reused := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Tandai terpakai secara atomik agar dua request paralel tidak sama-sama lolos
+		res := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", rt.ID).
+			Update("used_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			reused = true
+			return revokeSession(tx, sess.ID)
+		}
+		var err error
+		if refresh, err = issueRefreshToken(tx, &sess); err != nil {
+			return err
+		}
+		access, err = signAccessToken(user, sess.ID)
+		return err
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "refresh failed"})
+	}
+	if reused {
+		return c.Status(401).JSON(fiber.Map{"error": "refresh token reuse detected"})
+	}
+	return c.JSON(tokenPair(access, refresh))
+}
+
+// GET /users/me/sessions: daftar login yang masih aktif
+func GetSessions(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	current, _ := utils.GetSessionID(c)
+
+	var sessions []models.Session
+	if err := database.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", uid, time.Now()).
+		Order("last_seen_at desc").
+		Find(&sessions).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "query failed", "detail": err.Error()})
+	}
+
+	results := make([]fiber.Map, 0, len(sessions))
+	for _, s := range sessions {
+		results = append(results, fiber.Map{
+			"id":           s.ID,
+			"device":       s.Device,
+			"user_agent":   s.UserAgent,
+			"ip":           s.IP,
+			"created_at":   s.CreatedAt,
+			"last_seen_at": s.LastSeenAt,
+			"current":      s.ID == current,
+		})
+	}
+	return c.JSON(results)
+}
+
+// DELETE /users/me/sessions/:id: logout dari satu perangkat
+func RevokeSession(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	id := c.Params("id")
+
+	tx := database.DB.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, uid).
+		Update("revoked_at", time.Now())
+	if tx.Error != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "revoke failed", "detail": tx.Error.Error()})
+	}
+	if tx.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	return c.JSON(fiber.Map{"message": "session revoked"})
+}
+
+// DELETE /users/me/sessions: logout dari semua perangkat (termasuk sesi saat ini)
+func RevokeAllSessions(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	if err := revokeUserSessions(database.DB, uid, 0); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "revoke failed", "detail": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "all sessions revoked"})
+}
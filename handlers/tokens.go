@@ -0,0 +1,117 @@
+// handlers/tokens.go
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"finance/database"
+	"finance/models"
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func tokenJSON(t models.PersonalAccessToken) fiber.Map {
+	return fiber.Map{
+		"id":           t.ID,
+		"name":         t.Name,
+		"prefix":       t.Prefix,
+		"scopes":       strings.Fields(t.Scopes),
+		"last_used_at": t.LastUsedAt,
+		"expires_at":   t.ExpiresAt,
+		"created_at":   t.CreatedAt,
+	}
+}
+
+// POST /users/me/tokens: buat personal access token; nilai token hanya ditampilkan sekali
+func CreateToken(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	var body struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"` // 0 = tidak kedaluwarsa
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
+	}
+	if strings.TrimSpace(body.Name) == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "name cannot be empty"})
+	}
+	if len(body.Scopes) == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "at least one scope required", "available_scopes": utils.Scopes})
+	}
+	for _, s := range body.Scopes {
+		if !utils.ValidScope(s) {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid scope: " + s, "available_scopes": utils.Scopes})
+		}
+	}
+	if body.ExpiresInDays < 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "expires_in_days must be positive"})
+	}
+
+	secret, err := utils.RandomToken()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "create failed"})
+	}
+	raw := utils.PATPrefix + secret
+	t := models.PersonalAccessToken{
+		UserID:    uid,
+		Name:      body.Name,
+		Prefix:    raw[:len(utils.PATPrefix)+6],
+		TokenHash: utils.HashToken(raw),
+		Scopes:    strings.Join(body.Scopes, " "),
+	}
+	if body.ExpiresInDays > 0 {
+		exp := time.Now().AddDate(0, 0, body.ExpiresInDays)
+		t.ExpiresAt = &exp
+	}
+	if err := database.DB.Create(&t).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "create failed", "detail": err.Error()})
+	}
+
+	resp := tokenJSON(t)
+	resp["token"] = raw
+	return c.Status(201).JSON(resp)
+}
+
+// GET /users/me/tokens
+func GetTokens(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var tokens []models.PersonalAccessToken
+	if err := database.DB.Where("user_id = ? AND revoked_at IS NULL", uid).Order("created_at desc").Find(&tokens).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "query failed", "detail": err.Error()})
+	}
+	results := make([]fiber.Map, 0, len(tokens))
+	for _, t := range tokens {
+		results = append(results, tokenJSON(t))
+	}
+	return c.JSON(results)
+}
+
+// DELETE /users/me/tokens/:id
+func RevokeToken(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	id := c.Params("id")
+
+	tx := database.DB.Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, uid).
+		Update("revoked_at", time.Now())
+	if tx.Error != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "revoke failed", "detail": tx.Error.Error()})
+	}
+	if tx.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	return c.JSON(fiber.Map{"message": "token revoked"})
+}
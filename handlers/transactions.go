@@ -2,16 +2,39 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
+	"strings"
 	"time"
 
 	"finance/database"
 	"finance/models"
+	"finance/money"
+	"finance/services"
 	"finance/utils"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
+// invalidPayload membedakan nominal uang yang tidak valid (mis. lebih dari 2 desimal) dari payload rusak
+func invalidPayload(c *fiber.Ctx, err error) error {
+	if errors.Is(err, money.ErrInvalid) || errors.Is(err, money.ErrPrecision) || errors.Is(err, money.ErrRange) || errors.Is(err, money.ErrInvalidRate) {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
+}
+
+// checkAmount memastikan nominal > 0 dan muat di kolom decimal(15,2); "" berarti valid
+func checkAmount(field string, a money.Amount) string {
+	if a <= 0 {
+		return field + " must be greater than 0"
+	}
+	if a > money.Max {
+		return field + " is too large"
+	}
+	return ""
+}
+
 // FR-09..FR-13, FR-27..FR-28
 func CreateTransaction(c *fiber.Ctx) error {
 	uid, err := utils.GetUserID(c)
@@ -21,61 +44,69 @@ func CreateTransaction(c *fiber.Ctx) error {
 
 	// payload
 	var body struct {
-		CategoryID uint    `json:"category_id"`
-		Amount     float64 `json:"amount"`
-		Date       string  `json:"date"`
-		Note       string  `json:"note"`
+		CategoryID uint                 `json:"category_id"`
+		AccountID  *uint                `json:"account_id"`
+		Amount     money.Amount         `json:"amount"`
+		Date       string               `json:"date"`
+		Note       string               `json:"note"`
+		Currency   string               `json:"currency"`
+		Splits     []services.SplitLine `json:"splits"` // opsional: pecah ke beberapa kategori
 	}
 	if err := c.BodyParser(&body); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
+		return invalidPayload(c, err)
 	}
-	if body.Amount <= 0 {
-		return c.Status(400).JSON(fiber.Map{"error": "amount must be greater than 0"})
+	if msg := checkAmount("amount", body.Amount); msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
 	}
 	if body.Note == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "note cannot be empty"})
 	}
 
-	// validasi kategori
-	var cat models.Category
-	if err := database.DB.Where("id = ? AND user_id = ?", body.CategoryID, uid).First(&cat).Error; err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid category"})
-	}
-
 	// parse tanggal
 	parsed, err := time.Parse(time.RFC3339, body.Date)
 	if err != nil {
 		parsed = time.Now()
 	}
 
-	// buat transaksi
-	trx := models.Transaction{
-		UserID:     uid,
+	// validasi kategori/split, akun dan mata uang; jalur yang sama dipakai recurring & tagihan
+	trx, cat, err := services.BuildTransaction(database.DB, uid, services.NewTransaction{
 		CategoryID: body.CategoryID,
+		AccountID:  body.AccountID,
 		Amount:     body.Amount,
 		Date:       parsed,
 		Note:       body.Note,
+		Currency:   body.Currency,
+		Splits:     body.Splits,
+	})
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
-	if err := database.DB.Create(&trx).Error; err != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return services.CreateTransaction(tx, trx, body.Splits)
+	})
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "create failed", "detail": err.Error()})
 	}
 
-	// cek budget terkait
-	var budget models.Budget
-	if err := database.DB.Where("category_id = ? AND user_id = ?", trx.CategoryID, uid).First(&budget).Error; err == nil {
-		var totalExpense float64
-		database.DB.Model(&models.Transaction{}).
-			Where("category_id = ? AND user_id = ? AND date BETWEEN ? AND ?", trx.CategoryID, uid, budget.StartDate, budget.EndDate).
-			Select("COALESCE(SUM(amount),0)").Scan(&totalExpense)
-
-		status := calculateStatus(totalExpense, budget.LimitAmount)
-		if status == "Over Budget" {
-			// buat notifikasi
-			if err := AddBudgetNotification(uid, cat.Name); err != nil {
-				fmt.Println("Gagal simpan notifikasi:", err)
+	// transaksi split: cek budget tiap kategori baris
+	if len(body.Splits) > 0 {
+		budgets := []fiber.Map{}
+		for _, l := range body.Splits {
+			var lineCat models.Category
+			database.DB.First(&lineCat, l.CategoryID)
+			if status, total, ok := services.CheckCategoryBudget(database.DB, uid, lineCat); ok {
+				budgets = append(budgets, fiber.Map{"category_id": lineCat.ID, "budget_status": status, "total_expense": total})
 			}
 		}
+		return c.Status(201).JSON(fiber.Map{
+			"transaction": trx,
+			"splits":      body.Splits,
+			"budgets":     budgets,
+		})
+	}
 
+	// cek budget terkait
+	if status, totalExpense, ok := services.CheckCategoryBudget(database.DB, uid, *cat); ok {
 		return c.Status(201).JSON(fiber.Map{
 			"transaction":   trx,
 			"budget_status": status,
@@ -100,34 +131,48 @@ func GetTransactions(c *fiber.Ctx) error {
 	start := c.Query("start_date")
 	end := c.Query("end_date")
 	categoryID := c.Query("category_id")
+	accountID := c.Query("account_id")
 	keyword := c.Query("keyword")
 	limit := c.QueryInt("limit", 50)
 	offset := c.QueryInt("offset", 0)
 
 	// query with JOIN
 	var results []struct {
-		ID           uint    `json:"id"`
-		Amount       float64 `json:"amount"`
-		Note         string  `json:"note"`
-		Date         string  `json:"date"`
-		CategoryID   uint    `json:"category_id"`
-		CategoryName string  `json:"category_name"`
-		CategoryType string  `json:"category_type"`
+		ID           uint         `json:"id"`
+		Amount       money.Amount `json:"amount"`
+		Note         string       `json:"note"`
+		Date         string       `json:"date"`
+		CategoryID   uint         `json:"category_id"`
+		CategoryName string       `json:"category_name"`
+		CategoryType string       `json:"category_type"`
+		Currency     string       `json:"currency"`
+		AccountID    *uint        `json:"account_id"`
+		TransferID   *uint        `json:"transfer_id"`
+		IsSplit      bool         `json:"is_split"`
 	}
 
+	// Leg transfer tidak punya kategori; category_type-nya "transfer_out" / "transfer_in"
 	query := `
 		SELECT t.id, t.amount, t.note, t.date,
-		       c.id AS category_id, c.name AS category_name, c.type AS category_type
+		       t.category_id, COALESCE(c.name, '') AS category_name,
+		       COALESCE(c.type, 'transfer_' || t.transfer_leg) AS category_type,
+		       t.currency, t.account_id, t.transfer_id,
+		       EXISTS (SELECT 1 FROM transaction_splits s WHERE s.transaction_id = t.id) AS is_split
 		FROM transactions t
-		JOIN categories c ON t.category_id = c.id
+		LEFT JOIN categories c ON t.category_id = c.id
 		WHERE t.user_id = ?
 	`
 
 	args := []interface{}{uid}
 
 	if categoryID != "" {
-		query += " AND c.id = ?"
-		args = append(args, categoryID)
+		// transaksi split ikut bila salah satu barisnya di kategori ini
+		query += " AND (c.id = ? OR EXISTS (SELECT 1 FROM transaction_splits s WHERE s.transaction_id = t.id AND s.category_id = ?))"
+		args = append(args, categoryID, categoryID)
+	}
+	if accountID != "" {
+		query += " AND t.account_id = ?"
+		args = append(args, accountID)
 	}
 	if start != "" && end != "" {
 		query += " AND t.date BETWEEN ? AND ?"
@@ -158,7 +203,13 @@ func GetTransaction(c *fiber.Ctx) error {
 	if err := database.DB.Where("id = ? AND user_id = ?", id, uid).First(&trx).Error; err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "not found"})
 	}
-	return c.JSON(trx)
+	// bentuk response selalu sama: field transaksi + splits (kosong bila tidak di-split)
+	resp := struct {
+		models.Transaction
+		Splits []models.TransactionSplit `json:"splits"`
+	}{Transaction: trx, Splits: []models.TransactionSplit{}}
+	database.DB.Where("transaction_id = ?", trx.ID).Order("id").Find(&resp.Splits)
+	return c.JSON(resp)
 }
 
 func UpdateTransaction(c *fiber.Ctx) error {
@@ -174,22 +225,66 @@ func UpdateTransaction(c *fiber.Ctx) error {
 	}
 
 	var body struct {
-		CategoryID *uint    `json:"category_id"`
-		Amount     *float64 `json:"amount"`
-		Date       *string  `json:"date"`
-		Note       *string  `json:"note"`
+		CategoryID *uint         `json:"category_id"`
+		AccountID  *uint         `json:"account_id"` // 0 = lepas dari akun
+		Amount     *money.Amount `json:"amount"`
+		Date       *string       `json:"date"`
+		Note       *string       `json:"note"`
+		Currency   *string       `json:"currency"`
+		// nil = split tidak diubah, [] = hapus split, isi = ganti semua baris
+		Splits *[]services.SplitLine `json:"splits"`
 	}
 	if err := c.BodyParser(&body); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
+		return invalidPayload(c, err)
+	}
+	prevCategoryID := trx.CategoryID
+	if trx.TransferID != nil {
+		return updateTransferLeg(c, uid, trx, body.CategoryID != nil || body.AccountID != nil || body.Splits != nil || body.Currency != nil, body.Amount, body.Date, body.Note)
+	}
+	var splitCount int64
+	database.DB.Model(&models.TransactionSplit{}).Where("transaction_id = ?", trx.ID).Count(&splitCount)
+	if body.CategoryID != nil && splitCount > 0 && (body.Splits == nil || len(*body.Splits) > 0) {
+		return c.Status(400).JSON(fiber.Map{"error": "category of a split transaction is set by its lines"})
 	}
 	if body.CategoryID != nil {
 		var cat models.Category
 		if err := database.DB.Where("id = ? AND user_id = ?", *body.CategoryID, uid).First(&cat).Error; err != nil {
 			return c.Status(400).JSON(fiber.Map{"error": "invalid category"})
 		}
+		// kategori arsip hanya ditolak bila kategorinya diganti
+		if cat.ArchivedAt != nil && cat.ID != trx.CategoryID {
+			return c.Status(400).JSON(fiber.Map{"error": services.ErrArchivedCategory.Error()})
+		}
 		trx.CategoryID = *body.CategoryID
 	}
+	if body.AccountID != nil {
+		if *body.AccountID == 0 {
+			trx.AccountID = nil
+		} else if acc, ok := findAccount(uid, *body.AccountID); !ok {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid account"})
+		} else {
+			trx.AccountID = body.AccountID
+			if body.Currency == nil {
+				trx.Currency = acc.Currency
+			}
+		}
+	}
+	if body.Currency != nil {
+		trx.Currency = strings.ToUpper(*body.Currency)
+		if !services.ValidCurrency(trx.Currency) {
+			return c.Status(400).JSON(fiber.Map{"error": "currency must be a 3-letter ISO code"})
+		}
+	}
+	// transaksi di akun harus bermata uang sama dengan akunnya
+	if trx.AccountID != nil {
+		if acc, ok := findAccount(uid, *trx.AccountID); ok && acc.Currency != trx.Currency {
+			return c.Status(400).JSON(fiber.Map{"error": "currency must match account currency " + acc.Currency})
+		}
+	}
 	if body.Amount != nil {
+		if msg := checkAmount("amount", *body.Amount); msg != "" {
+			return c.Status(400).JSON(fiber.Map{"error": msg})
+		}
 		trx.Amount = *body.Amount
 	}
 	if body.Date != nil {
@@ -201,7 +296,55 @@ func UpdateTransaction(c *fiber.Ctx) error {
 		trx.Note = *body.Note
 	}
 
-	if err := database.DB.Save(&trx).Error; err != nil {
+	// Validasi split baru, atau pastikan split lama masih sama dengan jumlah transaksi
+	var lines []services.SplitLine
+	if body.Splits != nil {
+		lines = *body.Splits
+	} else if splitCount > 0 {
+		var splits []models.TransactionSplit
+		database.DB.Where("transaction_id = ?", trx.ID).Order("id").Find(&splits)
+		for _, s := range splits {
+			lines = append(lines, services.SplitLine{CategoryID: s.CategoryID, Amount: s.Amount, Note: s.Note})
+		}
+	}
+	if len(lines) > 0 {
+		first, err := services.ValidateSplits(database.DB, uid, trx.Amount, lines)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		trx.CategoryID = first.ID
+	}
+	// split baru: kategori arsip hanya ditolak bila belum dipakai transaksi ini
+	if body.Splits != nil && len(lines) > 0 {
+		current := map[uint]bool{prevCategoryID: true}
+		var used []uint
+		database.DB.Model(&models.TransactionSplit{}).Where("transaction_id = ?", trx.ID).Pluck("category_id", &used)
+		for _, id := range used {
+			current[id] = true
+		}
+		var added []uint
+		for _, l := range lines {
+			if !current[l.CategoryID] {
+				added = append(added, l.CategoryID)
+			}
+		}
+		if len(added) > 0 {
+			if err := services.CheckCategoriesActive(database.DB, uid, added...); err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+			}
+		}
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&trx).Error; err != nil {
+			return err
+		}
+		if body.Splits != nil {
+			return services.ReplaceSplits(tx, &trx, lines)
+		}
+		return nil
+	})
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "update failed"})
 	}
 	return c.JSON(trx)
@@ -213,8 +356,60 @@ func DeleteTransaction(c *fiber.Ctx) error {
 		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
 	}
 	id := c.Params("id")
-	if err := database.DB.Where("id = ? AND user_id = ?", id, uid).Delete(&models.Transaction{}).Error; err != nil {
+
+	// Menghapus salah satu leg transfer berarti menghapus transfernya (kedua leg)
+	var trx models.Transaction
+	if err := database.DB.Where("id = ? AND user_id = ? AND transfer_id IS NOT NULL", id, uid).First(&trx).Error; err == nil {
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			return services.DeleteTransfer(tx, &models.Transfer{ID: *trx.TransferID})
+		})
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "delete failed"})
+		}
+		return c.JSON(fiber.Map{"message": "deleted"})
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("transaction_id = ? AND user_id = ?", id, uid).Delete(&models.TransactionSplit{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ? AND user_id = ?", id, uid).Delete(&models.Transaction{}).Error
+	})
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "delete failed"})
 	}
 	return c.JSON(fiber.Map{"message": "deleted"})
 }
+
+// updateTransferLeg menerapkan perubahan jumlah/tanggal/catatan ke transfer induk agar kedua leg tetap sama
+func updateTransferLeg(c *fiber.Ctx, uid uint, leg models.Transaction, moved bool, amount *money.Amount, date, note *string) error {
+	if moved {
+		return c.Status(400).JSON(fiber.Map{"error": "transfer legs have no category; change accounts via PUT /transfers/:id"})
+	}
+	var t models.Transfer
+	if err := database.DB.Where("id = ? AND user_id = ?", *leg.TransferID, uid).First(&t).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	if amount != nil {
+		if msg := checkAmount("amount", *amount); msg != "" {
+			return c.Status(400).JSON(fiber.Map{"error": msg})
+		}
+		t.Amount = *amount
+	}
+	if date != nil {
+		if parsed, err := time.Parse(time.RFC3339, *date); err == nil {
+			t.Date = parsed
+		}
+	}
+	if note != nil {
+		t.Note = *note
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return services.UpdateTransfer(tx, &t)
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "update failed"})
+	}
+	database.DB.First(&leg, leg.ID)
+	return c.JSON(leg)
+}
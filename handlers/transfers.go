@@ -0,0 +1,194 @@
+// handlers/transfers.go
+package handlers
+
+import (
+	"time"
+
+	"finance/database"
+	"finance/models"
+	"finance/money"
+	"finance/services"
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// validateTransferAccounts memastikan kedua akun milik user, berbeda, dan bermata uang sama.
+// Mengembalikan mata uang transfer.
+func validateTransferAccounts(uid, fromID, toID uint) (string, *fiber.Error) {
+	if fromID == toID {
+		return "", fiber.NewError(400, "from_account_id and to_account_id must differ")
+	}
+	from, ok := findAccount(uid, fromID)
+	if !ok {
+		return "", fiber.NewError(400, "invalid from_account_id")
+	}
+	to, ok := findAccount(uid, toID)
+	if !ok {
+		return "", fiber.NewError(400, "invalid to_account_id")
+	}
+	if from.Currency != to.Currency {
+		return "", fiber.NewError(400, "accounts must use the same currency")
+	}
+	return from.Currency, nil
+}
+
+// POST /transfers: pindahkan uang antar akun (dua leg dibuat atomik)
+func CreateTransfer(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	var body struct {
+		FromAccountID uint         `json:"from_account_id"`
+		ToAccountID   uint         `json:"to_account_id"`
+		Amount        money.Amount `json:"amount"`
+		Date          string       `json:"date"`
+		Note          string       `json:"note"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return invalidPayload(c, err)
+	}
+	if msg := checkAmount("amount", body.Amount); msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
+	}
+	currency, ferr := validateTransferAccounts(uid, body.FromAccountID, body.ToAccountID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	parsed, err := time.Parse(time.RFC3339, body.Date)
+	if err != nil {
+		parsed = time.Now()
+	}
+
+	t := models.Transfer{
+		UserID:        uid,
+		FromAccountID: body.FromAccountID,
+		ToAccountID:   body.ToAccountID,
+		Amount:        body.Amount,
+		Date:          parsed,
+		Note:          body.Note,
+		Currency:      currency,
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return services.CreateTransfer(tx, &t)
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "create failed", "detail": err.Error()})
+	}
+	return c.Status(201).JSON(t)
+}
+
+// GET /transfers?account_id=
+func GetTransfers(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	limit := c.QueryInt("limit", 50)
+	offset := c.QueryInt("offset", 0)
+
+	query := database.DB.Where("user_id = ?", uid)
+	if accountID := c.QueryInt("account_id", 0); accountID > 0 {
+		query = query.Where("from_account_id = ? OR to_account_id = ?", accountID, accountID)
+	}
+	var transfers []models.Transfer
+	if err := query.Order("date desc").Limit(limit).Offset(offset).Find(&transfers).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "query failed", "detail": err.Error()})
+	}
+	return c.JSON(transfers)
+}
+
+// GET /transfers/:id
+func GetTransfer(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var t models.Transfer
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Params("id"), uid).First(&t).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	var legs []models.Transaction
+	database.DB.Where("transfer_id = ?", t.ID).Order("id").Find(&legs)
+	return c.JSON(fiber.Map{"transfer": t, "legs": legs})
+}
+
+// PUT /transfers/:id: perubahan selalu diterapkan ke kedua leg
+func UpdateTransfer(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var t models.Transfer
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Params("id"), uid).First(&t).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+
+	var body struct {
+		FromAccountID *uint         `json:"from_account_id"`
+		ToAccountID   *uint         `json:"to_account_id"`
+		Amount        *money.Amount `json:"amount"`
+		Date          *string       `json:"date"`
+		Note          *string       `json:"note"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return invalidPayload(c, err)
+	}
+	if body.FromAccountID != nil {
+		t.FromAccountID = *body.FromAccountID
+	}
+	if body.ToAccountID != nil {
+		t.ToAccountID = *body.ToAccountID
+	}
+	if body.FromAccountID != nil || body.ToAccountID != nil {
+		currency, ferr := validateTransferAccounts(uid, t.FromAccountID, t.ToAccountID)
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+		}
+		t.Currency = currency
+	}
+	if body.Amount != nil {
+		if msg := checkAmount("amount", *body.Amount); msg != "" {
+			return c.Status(400).JSON(fiber.Map{"error": msg})
+		}
+		t.Amount = *body.Amount
+	}
+	if body.Date != nil {
+		if parsed, err := time.Parse(time.RFC3339, *body.Date); err == nil {
+			t.Date = parsed
+		}
+	}
+	if body.Note != nil {
+		t.Note = *body.Note
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return services.UpdateTransfer(tx, &t)
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "update failed", "detail": err.Error()})
+	}
+	return c.JSON(t)
+}
+
+// DELETE /transfers/:id: hapus transfer beserta kedua leg-nya
+func DeleteTransfer(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var t models.Transfer
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Params("id"), uid).First(&t).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "not found"})
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return services.DeleteTransfer(tx, &t)
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "delete failed", "detail": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "deleted"})
+}
@@ -0,0 +1,307 @@
+// handlers/twofactor.go
+package handlers
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"finance/database"
+	"finance/jwtkeys"
+	"finance/models"
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	mfaChallengeTTL   = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+var errInvalidSecondFactor = errors.New("invalid second factor")
+
+func totpIssuer() string {
+	if v := os.Getenv("TOTP_ISSUER"); v != "" {
+		return v
+	}
+	return "Finance"
+}
+
+// completeLogin membuat sesi, atau challenge "mfa pending" bila user mengaktifkan 2FA
+func completeLogin(c *fiber.Ctx, user models.User, device string, userInfo fiber.Map) error {
+	if user.DisabledAt != nil {
+		return c.Status(403).JSON(fiber.Map{"error": "account disabled"})
+	}
+	if user.PasswordResetRequired {
+		return c.Status(403).JSON(fiber.Map{"error": "password reset required, check your email"})
+	}
+	if user.TOTPEnabled {
+		// Token challenge tidak punya "sid" sehingga ditolak middleware JWT
+		challenge, err := jwtkeys.Keys.Sign(jwt.MapClaims{
+			"user_id": user.ID,
+			"typ":     "mfa_pending",
+			"device":  device,
+			"exp":     time.Now().Add(mfaChallengeTTL).Unix(),
+		})
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "login failed"})
+		}
+		return c.JSON(fiber.Map{"mfa_required": true, "mfa_token": challenge})
+	}
+
+	tokens, err := startSession(c, user, device)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "login failed"})
+	}
+	if cancelScheduledDeletion(&user) {
+		tokens["deletion_cancelled"] = true
+	}
+	tokens["user"] = userInfo
+	return c.JSON(tokens)
+}
+
+// checkSecondFactor menerima kode TOTP atau recovery code (yang lalu ditandai terpakai)
+func checkSecondFactor(tx *gorm.DB, user *models.User, code string) (bool, error) {
+	if step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep); ok {
+		res := tx.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		return res.RowsAffected == 1, res.Error
+	}
+
+	res := tx.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, utils.HashToken(utils.NormalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	return res.RowsAffected == 1, res.Error
+}
+
+// replaceRecoveryCodes menghapus kode lama dan mengembalikan kode baru (plaintext, hanya sekali)
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := utils.NewRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		rc := models.RecoveryCode{UserID: userID, CodeHash: utils.HashToken(utils.NormalizeRecoveryCode(code))}
+		if err := tx.Create(&rc).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// POST /auth/2fa/verify: tukar mfa_token + kode 2FA dengan pasangan token
+func VerifyTwoFactor(c *fiber.Ctx) error {
+	var body struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.MFAToken == "" || body.Code == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "mfa_token/code required"})
+	}
+
+	token, err := jwt.Parse(body.MFAToken, jwtkeys.Keys.Keyfunc)
+	if err != nil || !token.Valid {
+		return c.Status(401).JSON(fiber.Map{"error": "invalid or expired mfa token"})
+	}
+	claims, _ := token.Claims.(jwt.MapClaims)
+	uid, _ := claims["user_id"].(float64)
+	device, _ := claims["device"].(string)
+	if claims["typ"] != "mfa_pending" || uid == 0 {
+		return c.Status(401).JSON(fiber.Map{"error": "invalid or expired mfa token"})
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, uint(uid)).Error; err != nil || !user.TOTPEnabled || user.DisabledAt != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "invalid or expired mfa token"})
+	}
+	// Kode 2FA yang salah dihitung sebagai login gagal untuk akun yang sama
+	if throttled, err := loginThrottled(c, user.Email); throttled {
+		return err
+	}
+	ok, err := checkSecondFactor(database.DB, &user, body.Code)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "verify failed"})
+	}
+	if !ok {
+		recordLoginFailure(c, user.Email, &user)
+		return c.Status(401).JSON(fiber.Map{"error": "invalid code"})
+	}
+	recordLoginSuccess(user.Email)
+
+	tokens, err := startSession(c, user, device)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "login failed"})
+	}
+	if cancelScheduledDeletion(&user) {
+		tokens["deletion_cancelled"] = true
+	}
+	tokens["user"] = fiber.Map{"id": user.ID, "email": user.Email, "name": user.Name, "photo_url": user.PhotoURL}
+	return c.JSON(tokens)
+}
+
+// POST /users/me/2fa/totp: mulai enrollment, kembalikan secret dan otpauth URI
+func EnrollTOTP(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var user models.User
+	if err := database.DB.First(&user, uid).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+	}
+	if user.TOTPEnabled {
+		return c.Status(400).JSON(fiber.Map{"error": "2fa already enabled"})
+	}
+
+	secret, err := utils.NewTOTPSecret()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "enroll failed"})
+	}
+	if err := database.DB.Model(&user).Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "enroll failed"})
+	}
+	return c.Status(201).JSON(fiber.Map{
+		"secret":      secret,
+		"otpauth_uri": utils.TOTPURI(totpIssuer(), user.Email, secret),
+	})
+}
+
+// POST /users/me/2fa/totp/confirm: aktifkan 2FA dengan kode pertama dari aplikasi authenticator
+func ConfirmTOTP(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Code == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "code required"})
+	}
+	var user models.User
+	if err := database.DB.First(&user, uid).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+	}
+	if user.TOTPEnabled {
+		return c.Status(400).JSON(fiber.Map{"error": "2fa already enabled"})
+	}
+	if user.TOTPSecret == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "2fa enrollment not started"})
+	}
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, body.Code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid code"})
+	}
+
+	var codes []string
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{"totp_enabled": true, "totp_last_step": step}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "confirm failed"})
+	}
+	return c.JSON(fiber.Map{"message": "2fa enabled", "recovery_codes": codes})
+}
+
+// DELETE /users/me/2fa/totp: nonaktifkan 2FA, butuh password atau kode 2FA
+func DisableTOTP(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var body struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
+	}
+	var user models.User
+	if err := database.DB.First(&user, uid).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+	}
+	if !user.TOTPEnabled {
+		return c.Status(400).JSON(fiber.Map{"error": "2fa not enabled"})
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		ok := user.PasswordHash != "" && body.Password != "" &&
+			bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(body.Password)) == nil
+		if !ok && body.Code != "" {
+			var err error
+			if ok, err = checkSecondFactor(tx, &user, body.Code); err != nil {
+				return err
+			}
+		}
+		if !ok {
+			return errInvalidSecondFactor
+		}
+		if err := tx.Model(&user).Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err == errInvalidSecondFactor {
+		return c.Status(401).JSON(fiber.Map{"error": "invalid password or code"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "disable failed"})
+	}
+	return c.JSON(fiber.Map{"message": "2fa disabled"})
+}
+
+// POST /users/me/2fa/recovery-codes: buat ulang recovery code (kode lama hangus)
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Code == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "code required"})
+	}
+	var user models.User
+	if err := database.DB.First(&user, uid).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+	}
+	if !user.TOTPEnabled {
+		return c.Status(400).JSON(fiber.Map{"error": "2fa not enabled"})
+	}
+
+	var codes []string
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		ok, err := checkSecondFactor(tx, &user, body.Code)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errInvalidSecondFactor
+		}
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err == errInvalidSecondFactor {
+		return c.Status(401).JSON(fiber.Map{"error": "invalid code"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "regenerate failed"})
+	}
+	return c.JSON(fiber.Map{"recovery_codes": codes})
+}
@@ -2,8 +2,13 @@
 package handlers
 
 import (
+	"errors"
+	"log"
+	"strings"
+
 	"finance/database"
 	"finance/models"
+	"finance/services"
 	"finance/utils"
 
 	"github.com/gofiber/fiber/v2"
@@ -20,7 +25,7 @@ func GetMe(c *fiber.Ctx) error {
 	if err := database.DB.First(&user, uid).Error; err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": "user not found"})
 	}
-	return c.JSON(fiber.Map{"id": user.ID, "name": user.Name, "email": user.Email, "photo_url": user.PhotoURL})
+	return c.JSON(fiber.Map{"id": user.ID, "name": user.Name, "email": user.Email, "email_verified": user.EmailVerifiedAt != nil, "photo_url": user.PhotoURL, "base_currency": user.BaseCurrency})
 }
 
 func UpdateMe(c *fiber.Ctx) error {
@@ -32,6 +37,8 @@ func UpdateMe(c *fiber.Ctx) error {
 		Name     *string `json:"name"`
 		Email    *string `json:"email"`
 		PhotoURL *string `json:"photo_url"`
+		// mata uang dasar untuk laporan & budget
+		BaseCurrency *string `json:"base_currency"`
 	}
 	if err := c.BodyParser(&body); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid payload"})
@@ -43,15 +50,50 @@ func UpdateMe(c *fiber.Ctx) error {
 	if body.Name != nil {
 		user.Name = *body.Name
 	}
-	if body.Email != nil {
+	emailChanged := false
+	if body.Email != nil && *body.Email != user.Email {
+		if *body.Email == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "email cannot be empty"})
+		}
+		var count int64
+		database.DB.Model(&models.User{}).Where("email = ? AND id <> ?", *body.Email, uid).Count(&count)
+		if count > 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "email already in use"})
+		}
+		// Email baru harus diverifikasi ulang
 		user.Email = *body.Email
+		user.EmailVerifiedAt = nil
+		emailChanged = true
 	}
 	if body.PhotoURL != nil {
 		user.PhotoURL = *body.PhotoURL
 	}
+	if body.BaseCurrency != nil {
+		cur := strings.ToUpper(*body.BaseCurrency)
+		if !services.ValidCurrency(cur) {
+			return c.Status(400).JSON(fiber.Map{"error": "base_currency must be a 3-letter ISO code"})
+		}
+		user.BaseCurrency = cur
+	}
 	if err := database.DB.Save(&user).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "update failed"})
 	}
+	if emailChanged {
+		if err := sendVerificationEmail(user); err != nil {
+			log.Println("Gagal kirim email verifikasi:", err)
+			if errors.Is(err, errVerificationThrottled) {
+				return c.JSON(fiber.Map{
+					"message":           "updated, but too many verification emails were sent; request a new one later via /users/me/verify-email/resend",
+					"verification_sent": false,
+				})
+			}
+			return c.JSON(fiber.Map{
+				"message":           "updated, but the verification email could not be sent; request a new one via /users/me/verify-email/resend",
+				"verification_sent": false,
+			})
+		}
+		return c.JSON(fiber.Map{"message": "updated, please verify your new email", "verification_sent": true})
+	}
 	return c.JSON(fiber.Map{"message": "updated"})
 }
 
@@ -80,5 +122,11 @@ func ChangePassword(c *fiber.Ctx) error {
 	if err := database.DB.Save(&user).Error; err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "update failed"})
 	}
+
+	// Password berganti: paksa login ulang di semua perangkat lain
+	sid, _ := utils.GetSessionID(c)
+	if err := revokeUserSessions(database.DB, uid, sid); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "revoke sessions failed"})
+	}
 	return c.JSON(fiber.Map{"message": "password changed"})
 }
@@ -0,0 +1,127 @@
+// handlers/verification.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"finance/database"
+	"finance/mailer"
+	"finance/models"
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	emailVerificationTTL = 24 * time.Hour
+	// jeda minimal antar pengiriman dan batas kirim per jam
+	verificationCooldown  = time.Minute
+	verificationHourlyMax = 5
+)
+
+var errVerificationThrottled = errors.New("verification email throttled")
+
+// sendVerificationEmail membuat token verifikasi untuk email user saat ini dan mengirimkannya.
+// Jeda antar pengiriman hanya berlaku untuk alamat yang sama, sehingga email baru setelah
+// ganti alamat selalu mendapat token (batas per jam tetap berlaku).
+func sendVerificationEmail(user models.User) error {
+	var last models.EmailVerificationToken
+	if err := database.DB.Where("user_id = ?", user.ID).Order("created_at desc").First(&last).Error; err == nil &&
+		last.Email == user.Email && time.Since(last.CreatedAt) < verificationCooldown {
+		return errVerificationThrottled
+	}
+	var count int64
+	database.DB.Model(&models.EmailVerificationToken{}).
+		Where("user_id = ? AND created_at > ?", user.ID, time.Now().Add(-time.Hour)).
+		Count(&count)
+	if count >= verificationHourlyMax {
+		return errVerificationThrottled
+	}
+
+	raw, err := utils.RandomToken()
+	if err != nil {
+		return err
+	}
+	if err := database.DB.Create(&models.EmailVerificationToken{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: time.Now().Add(emailVerificationTTL),
+	}).Error; err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("Halo %s,\n\nKonfirmasi email Anda dengan token berikut (berlaku 24 jam):\n\n%s\n", user.Name, raw)
+	if link := appLink("/verify-email", raw); link != "" {
+		msg += "\nAtau buka: " + link + "\n"
+	}
+	return mailer.Send(mailer.Message{To: user.Email, Subject: "Verifikasi email", Body: msg})
+}
+
+// POST /auth/verify-email: konfirmasi email memakai token dari email
+func VerifyEmail(c *fiber.Ctx) error {
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Token == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "token required"})
+	}
+
+	var vt models.EmailVerificationToken
+	if err := database.DB.Where("token_hash = ?", utils.HashToken(body.Token)).First(&vt).Error; err != nil ||
+		vt.UsedAt != nil || time.Now().After(vt.ExpiresAt) {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid or expired token"})
+	}
+
+	invalid := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.EmailVerificationToken{}).
+			Where("id = ? AND used_at IS NULL", vt.ID).
+			Update("used_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		// Token untuk email lama tidak berlaku bila user sudah mengganti email lagi
+		res = tx.Model(&models.User{}).
+			Where("id = ? AND email = ?", vt.UserID, vt.Email).
+			Update("email_verified_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		invalid = res.RowsAffected == 0
+		return nil
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "verify failed"})
+	}
+	if invalid {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid or expired token"})
+	}
+	return c.JSON(fiber.Map{"message": "email verified"})
+}
+
+// POST /users/me/verify-email/resend: kirim ulang email verifikasi (dibatasi)
+func ResendVerification(c *fiber.Ctx) error {
+	uid, err := utils.GetUserID(c)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var user models.User
+	if err := database.DB.First(&user, uid).Error; err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "user not found"})
+	}
+	if user.EmailVerifiedAt != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "email already verified"})
+	}
+
+	if err := sendVerificationEmail(user); err != nil {
+		if errors.Is(err, errVerificationThrottled) {
+			return c.Status(429).JSON(fiber.Map{"error": "too many requests, try again later"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "send failed"})
+	}
+	return c.JSON(fiber.Map{"message": "verification email sent"})
+}
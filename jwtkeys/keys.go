@@ -0,0 +1,255 @@
+// jwtkeys/keys.go
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Key adalah satu kunci JWT. Kunci tanpa signKey hanya dipakai untuk verifikasi
+// (misalnya kunci lama yang sedang dirotasi keluar).
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// KeySet berisi kunci aktif untuk menandatangani token dan semua kunci yang
+// masih diterima untuk verifikasi, diindeks berdasarkan kid.
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+var Keys *KeySet
+
+// keyConfig adalah format satu entri pada file JWT_KEYS_FILE
+type keyConfig struct {
+	Kid            string `json:"kid"`
+	Alg            string `json:"alg"`
+	Secret         string `json:"secret"`
+	PrivateKeyFile string `json:"private_key_file"`
+	PublicKeyFile  string `json:"public_key_file"`
+}
+
+type fileConfig struct {
+	ActiveKid string      `json:"active_kid"`
+	Keys      []keyConfig `json:"keys"`
+}
+
+// Load membaca konfigurasi kunci dari environment:
+//   - JWT_KEYS_FILE: file JSON {"active_kid": "...", "keys": [...]} untuk rotasi
+//   - atau satu kunci: JWT_ALG (HS256/RS256/EdDSA), JWT_KID, dan JWT_SECRET /
+//     JWT_PRIVATE_KEY_FILE
+//
+// Tanpa konfigurasi sama sekali dipakai secret HS256 acak (hanya untuk
+// development; semua token hilang saat restart).
+func Load() {
+	var err error
+	Keys, err = load()
+	if err != nil {
+		log.Fatal("Failed to load JWT keys:", err)
+	}
+	log.Println("JWT signing key loaded, kid=" + Keys.active.ID)
+}
+
+func load() (*KeySet, error) {
+	if path := os.Getenv("JWT_KEYS_FILE"); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var cfg fileConfig
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		return newKeySet(cfg.ActiveKid, cfg.Keys)
+	}
+
+	kc := keyConfig{
+		Kid:            os.Getenv("JWT_KID"),
+		Alg:            os.Getenv("JWT_ALG"),
+		Secret:         os.Getenv("JWT_SECRET"),
+		PrivateKeyFile: os.Getenv("JWT_PRIVATE_KEY_FILE"),
+	}
+	if kc.Kid == "" {
+		kc.Kid = "default"
+	}
+	if kc.Alg == "" {
+		kc.Alg = "HS256"
+	}
+	if kc.Secret == "" && kc.PrivateKeyFile == "" {
+		log.Println("WARNING: JWT_SECRET not set, using an ephemeral random secret")
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		kc.Secret = base64.RawURLEncoding.EncodeToString(b)
+		kc.Kid = "ephemeral"
+	}
+	return newKeySet(kc.Kid, []keyConfig{kc})
+}
+
+func newKeySet(activeKid string, configs []keyConfig) (*KeySet, error) {
+	ks := &KeySet{keys: map[string]*Key{}}
+	for _, kc := range configs {
+		k, err := parseKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", kc.Kid, err)
+		}
+		if _, dup := ks.keys[k.ID]; dup {
+			return nil, fmt.Errorf("duplicate kid %q", k.ID)
+		}
+		ks.keys[k.ID] = k
+	}
+
+	active, ok := ks.keys[activeKid]
+	if !ok {
+		return nil, fmt.Errorf("active kid %q not found", activeKid)
+	}
+	if active.signKey == nil {
+		return nil, fmt.Errorf("active kid %q has no private key", activeKid)
+	}
+	ks.active = active
+	return ks, nil
+}
+
+func parseKey(kc keyConfig) (*Key, error) {
+	if kc.Kid == "" {
+		return nil, errors.New("kid required")
+	}
+	method := jwt.GetSigningMethod(kc.Alg)
+	k := &Key{ID: kc.Kid, Method: method}
+
+	switch method {
+	case jwt.SigningMethodHS256, jwt.SigningMethodHS384, jwt.SigningMethodHS512:
+		if kc.Secret == "" {
+			return nil, errors.New("secret required for " + kc.Alg)
+		}
+		k.signKey = []byte(kc.Secret)
+		k.verifyKey = []byte(kc.Secret)
+
+	case jwt.SigningMethodRS256, jwt.SigningMethodRS384, jwt.SigningMethodRS512:
+		if kc.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			priv, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			k.signKey = priv
+			k.verifyKey = &priv.PublicKey
+		} else {
+			pem, err := os.ReadFile(kc.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			if k.verifyKey, err = jwt.ParseRSAPublicKeyFromPEM(pem); err != nil {
+				return nil, err
+			}
+		}
+
+	case jwt.SigningMethodEdDSA:
+		if kc.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			priv, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			k.signKey = priv
+			k.verifyKey = priv.(crypto.Signer).Public()
+		} else {
+			pem, err := os.ReadFile(kc.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			if k.verifyKey, err = jwt.ParseEdPublicKeyFromPEM(pem); err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported alg %q", kc.Alg)
+	}
+	return k, nil
+}
+
+// Sign menandatangani claims dengan kunci aktif dan menambahkan header kid
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.active.Method, claims)
+	token.Header["kid"] = ks.active.ID
+	return token.SignedString(ks.active.signKey)
+}
+
+// Keyfunc memilih kunci verifikasi berdasarkan header kid dan memastikan
+// algoritma token sama dengan algoritma kunci tersebut.
+func (ks *KeySet) Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	k, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if t.Method.Alg() != k.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+	}
+	return k.verifyKey, nil
+}
+
+// JWK adalah representasi publik sebuah kunci (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS mengembalikan semua kunci publik (RSA/EdDSA). Secret HMAC tidak pernah dipublikasikan.
+func (ks *KeySet) JWKS() map[string][]JWK {
+	keys := []JWK{}
+	for _, k := range ks.keys {
+		switch pub := k.verifyKey.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, JWK{
+				Kty: "RSA",
+				Kid: k.ID,
+				Alg: k.Method.Alg(),
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, JWK{
+				Kty: "OKP",
+				Kid: k.ID,
+				Alg: k.Method.Alg(),
+				Use: "sig",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Kid < keys[j].Kid })
+	return map[string][]JWK{"keys": keys}
+}
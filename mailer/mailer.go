@@ -0,0 +1,173 @@
+// mailer/mailer.go
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer adalah pengirim email; implementasi dipilih lewat env MAIL_DRIVER
+type Mailer interface {
+	Send(msg Message) error
+}
+
+var Default Mailer
+
+// Init memilih implementasi mailer dari environment:
+//   - MAIL_DRIVER=smtp: SMTP_HOST, SMTP_PORT, SMTP_USER, SMTP_PASSWORD, MAIL_FROM
+//   - MAIL_DRIVER=file: tiap email ditulis sebagai file .eml di MAIL_DIR (default ./mail)
+//   - MAIL_DRIVER=log: isi email dicetak ke log, hanya untuk development (berisi token)
+//
+// Tanpa MAIL_DRIVER: APP_ENV=development memakai driver log; selain itu email tidak dikirim
+// dan hanya penerima + subjek yang dicatat (RedactedMailer), agar deployment lama tetap
+// jalan tanpa token reset password dan verifikasi tercetak ke log produksi.
+func Init() {
+	driver := os.Getenv("MAIL_DRIVER")
+	if driver == "" && os.Getenv("APP_ENV") == "development" {
+		driver = "log"
+	}
+	switch driver {
+	case "":
+		log.Println("PERINGATAN: MAIL_DRIVER belum diatur, email TIDAK dikirim (reset password, verifikasi, penghapusan akun). Atur MAIL_DRIVER=smtp atau file.")
+		Default = RedactedMailer{}
+	case "smtp":
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		Default = &SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "./mail"
+		}
+		Default = &FileMailer{Dir: dir, From: os.Getenv("MAIL_FROM")}
+	case "log":
+		log.Println("MAIL_DRIVER=log: isi email (termasuk token) dicetak ke log, jangan dipakai di produksi")
+		Default = LogMailer{}
+	default:
+		log.Fatalf("MAIL_DRIVER %q tidak dikenal: gunakan smtp, file, atau log (APP_ENV=development)", driver)
+	}
+}
+
+// Send mengirim lewat Default mailer
+func Send(msg Message) error {
+	if Default == nil {
+		Init()
+	}
+	return Default.Send(msg)
+}
+
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{msg.To}, format(m.From, msg))
+}
+
+// FileMailer menulis setiap email ke Dir, berguna untuk development lokal
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitize(msg.To))
+	return os.WriteFile(filepath.Join(m.Dir, name), format(m.From, msg), 0o644)
+}
+
+// LogMailer mencetak email ke log tanpa menyimpannya (development)
+type LogMailer struct{}
+
+func (LogMailer) Send(msg Message) error {
+	log.Printf("mail to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// ErrNotConfigured dikembalikan RedactedMailer: email tidak terkirim karena MAIL_DRIVER kosong
+var ErrNotConfigured = errors.New("mail driver not configured")
+
+// RedactedMailer tidak mengirim email; hanya penerima dan subjek yang dicatat, isi (token) tidak
+type RedactedMailer struct{}
+
+func (RedactedMailer) Send(msg Message) error {
+	log.Printf("mail tidak dikirim (MAIL_DRIVER belum diatur): to=%s subject=%q", msg.To, msg.Subject)
+	return ErrNotConfigured
+}
+
+// MemoryMailer menyimpan email yang terkirim, khusus untuk test (pasang lewat mailer.Default)
+type MemoryMailer struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (m *MemoryMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Sent mengembalikan salinan semua email yang sudah dikirim
+func (m *MemoryMailer) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.sent...)
+}
+
+// headerSafe membuang CR/LF agar nilai header tidak bisa menyisipkan header lain
+var headerSafe = strings.NewReplacer("\r", "", "\n", "")
+
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	if from != "" {
+		b.WriteString("From: " + headerSafe.Replace(from) + "\r\n")
+	}
+	b.WriteString("To: " + headerSafe.Replace(msg.To) + "\r\n")
+	b.WriteString("Subject: " + headerSafe.Replace(msg.Subject) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(msg.Body)
+	return []byte(b.String())
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, s)
+}
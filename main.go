@@ -1,12 +1,19 @@
 package main
 
 import (
-	"os"
 	"log"
+	"os"
+	"time"
 
 	"finance/database"
 	"finance/handlers"
+	"finance/jwtkeys"
+	"finance/mailer"
 	"finance/middleware"
+	"finance/models"
+	"finance/oidc"
+	"finance/services"
+	"finance/storage"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -14,6 +21,18 @@ import (
 
 func main() {
 	database.Connect()
+	services.SeedCategoryTemplates(database.DB)
+	jwtkeys.Load()
+	mailer.Init()
+	storage.Init()
+	services.InitRateProvider()
+	oidc.LoadProviders()
+	services.InitLoginGuard(database.DB)
+	services.StartAccountPurger(database.DB, time.Hour)
+	services.StartBackupScheduler(database.DB, time.Minute)
+	services.StartRecurringWorker(database.DB, time.Minute)
+	services.StartBillReminder(database.DB, time.Minute)
+	services.StartLoginAttemptPruner(time.Hour)
 
 	app := fiber.New()
 	app.Use(cors.New(cors.Config{
@@ -25,53 +44,161 @@ func main() {
 	app.Post("/auth/register", handlers.Register)
 	app.Post("/auth/login", handlers.Login)
 	app.Post("/auth/google", handlers.GoogleLogin)
+	app.Post("/auth/oidc/:provider", handlers.OIDCLogin)
+	app.Post("/auth/refresh", handlers.RefreshToken)
 	app.Post("/auth/logout", handlers.Logout)
+	app.Post("/auth/password/forgot", handlers.ForgotPassword)
+	app.Post("/auth/password/reset", handlers.ResetPassword)
+	app.Post("/auth/verify-email", handlers.VerifyEmail)
+	app.Post("/auth/2fa/verify", handlers.VerifyTwoFactor)
+	app.Get("/.well-known/jwks.json", handlers.JWKS)
 	app.Static("/uploads", "./uploads")
 
 	// Protected routes
-	app.Use(middleware.JWT(handlers.JwtSecret()))
+	app.Use(middleware.JWT(jwtkeys.Keys))
 
 	// Me
 	app.Get("/users/me", handlers.GetMe)
 	app.Put("/users/me", handlers.UpdateMe)
 	app.Put("/users/me/password", handlers.ChangePassword)
+	app.Get("/users/me/sessions", handlers.GetSessions)
+	app.Delete("/users/me/sessions", handlers.RevokeAllSessions)
+	app.Delete("/users/me/sessions/:id", handlers.RevokeSession)
+	app.Post("/users/me/verify-email/resend", handlers.ResendVerification)
+	app.Get("/users/me/export", handlers.ExportMyData)
+	app.Delete("/users/me", handlers.DeleteMe)
+
+	// Akun yang emailnya belum terverifikasi hanya boleh membaca data
+	app.Use(middleware.VerifiedEmail())
+
+	// Linked login providers
+	app.Get("/users/me/identities", handlers.GetIdentities)
+	app.Post("/users/me/identities/:provider", handlers.LinkIdentity)
+	app.Delete("/users/me/identities/:provider", handlers.UnlinkIdentity)
+
+	// Personal access tokens
+	app.Get("/users/me/tokens", handlers.GetTokens)
+	app.Post("/users/me/tokens", handlers.CreateToken)
+	app.Delete("/users/me/tokens/:id", handlers.RevokeToken)
+
+	// Two-factor
+	app.Post("/users/me/2fa/totp", handlers.EnrollTOTP)
+	app.Post("/users/me/2fa/totp/confirm", handlers.ConfirmTOTP)
+	app.Delete("/users/me/2fa/totp", handlers.DisableTOTP)
+	app.Post("/users/me/2fa/recovery-codes", handlers.RegenerateRecoveryCodes)
+
+	// Route di bawah ini juga bisa diakses personal access token dengan scope yang sesuai
 
 	// Categories
-	app.Post("/categories", handlers.CreateCategory)
-	app.Get("/categories", handlers.GetCategories)
-	app.Put("/categories/:id", handlers.UpdateCategory)
-	app.Delete("/categories/:id", handlers.DeleteCategory)
+	app.Post("/categories", middleware.Scope("categories:write"), handlers.CreateCategory)
+	app.Get("/categories", middleware.Scope("categories:read"), handlers.GetCategories)
+	app.Get("/categories/templates", middleware.Scope("categories:read"), handlers.GetCategoryTemplates)
+	app.Post("/categories/templates/apply", middleware.Scope("categories:write"), handlers.ApplyCategoryTemplate)
+	app.Put("/categories/:id", middleware.Scope("categories:write"), handlers.UpdateCategory)
+	app.Delete("/categories/:id", middleware.Scope("categories:write"), handlers.DeleteCategory)
+	app.Post("/categories/:id/merge", middleware.Scope("categories:write"), handlers.MergeCategory)
+	app.Post("/categories/:id/archive", middleware.Scope("categories:write"), handlers.ArchiveCategory)
+	app.Delete("/categories/:id/archive", middleware.Scope("categories:write"), handlers.UnarchiveCategory)
 
 	// Transactions
-	app.Post("/transactions", handlers.CreateTransaction)
-	app.Get("/transactions", handlers.GetTransactions)
-	app.Get("/transactions/:id", handlers.GetTransaction)
-	app.Put("/transactions/:id", handlers.UpdateTransaction)
-	app.Delete("/transactions/:id", handlers.DeleteTransaction)
+	app.Post("/transactions", middleware.Scope("transactions:write"), handlers.CreateTransaction)
+	app.Get("/transactions", middleware.Scope("transactions:read"), handlers.GetTransactions)
+	app.Get("/transactions/:id", middleware.Scope("transactions:read"), handlers.GetTransaction)
+	app.Put("/transactions/:id", middleware.Scope("transactions:write"), handlers.UpdateTransaction)
+	app.Delete("/transactions/:id", middleware.Scope("transactions:write"), handlers.DeleteTransaction)
+
+	// Accounts
+	app.Post("/accounts", middleware.Scope("accounts:write"), handlers.CreateAccount)
+	app.Get("/accounts", middleware.Scope("accounts:read"), handlers.GetAccounts)
+	app.Get("/accounts/:id", middleware.Scope("accounts:read"), handlers.GetAccount)
+	app.Put("/accounts/:id", middleware.Scope("accounts:write"), handlers.UpdateAccount)
+	app.Delete("/accounts/:id", middleware.Scope("accounts:write"), handlers.DeleteAccount)
+	app.Get("/accounts/:id/transactions", middleware.Scope("accounts:read"), handlers.GetAccountLedger)
+
+	// Transaksi berulang (gaji, sewa, langganan)
+	app.Post("/recurring", middleware.Scope("transactions:write"), handlers.CreateRecurring)
+	app.Post("/recurring/preview", middleware.Scope("transactions:read"), handlers.PreviewRecurringRule)
+	app.Get("/recurring", middleware.Scope("transactions:read"), handlers.GetRecurring)
+	app.Get("/recurring/:id", middleware.Scope("transactions:read"), handlers.GetRecurringDetail)
+	app.Put("/recurring/:id", middleware.Scope("transactions:write"), handlers.UpdateRecurring)
+	app.Delete("/recurring/:id", middleware.Scope("transactions:write"), handlers.DeleteRecurring)
+	app.Get("/recurring/:id/preview", middleware.Scope("transactions:read"), handlers.PreviewRecurring)
+	app.Put("/recurring/:id/occurrences/:date", middleware.Scope("transactions:write"), handlers.UpdateOccurrence)
+	app.Delete("/recurring/:id/occurrences/:date", middleware.Scope("transactions:write"), handlers.ResetOccurrence)
+
+	// Tagihan & pengingat jatuh tempo (/bills/upcoming harus sebelum /bills/:id)
+	app.Get("/bills/upcoming", middleware.Scope("bills:read"), handlers.GetUpcomingBills)
+	app.Post("/bills", middleware.Scope("bills:write"), handlers.CreateBill)
+	app.Get("/bills", middleware.Scope("bills:read"), handlers.GetBills)
+	app.Get("/bills/:id", middleware.Scope("bills:read"), handlers.GetBill)
+	app.Put("/bills/:id", middleware.Scope("bills:write"), handlers.UpdateBill)
+	app.Delete("/bills/:id", middleware.Scope("bills:write"), handlers.DeleteBill)
+	app.Post("/bills/:id/pay", middleware.Scope("bills:write"), handlers.PayBill)
+
+	// Transfers antar akun
+	app.Post("/transfers", middleware.Scope("transactions:write"), handlers.CreateTransfer)
+	app.Get("/transfers", middleware.Scope("transactions:read"), handlers.GetTransfers)
+	app.Get("/transfers/:id", middleware.Scope("transactions:read"), handlers.GetTransfer)
+	app.Put("/transfers/:id", middleware.Scope("transactions:write"), handlers.UpdateTransfer)
+	app.Delete("/transfers/:id", middleware.Scope("transactions:write"), handlers.DeleteTransfer)
 
 	// Reports
-	app.Get("/reports/summary", handlers.GetSummary)
-	app.Get("/reports/monthly", handlers.GetMonthlySummary)
-	app.Get("/reports/expense-by-category", handlers.GetExpenseByCategory)
+	app.Get("/reports/summary", middleware.Scope("reports:read"), handlers.GetSummary)
+	app.Get("/reports/monthly", middleware.Scope("reports:read"), handlers.GetMonthlySummary)
+	app.Get("/reports/expense-by-category", middleware.Scope("reports:read"), handlers.GetExpenseByCategory)
+
+	// Kurs mata uang
+	app.Get("/exchange-rates", middleware.Scope("reports:read"), handlers.GetExchangeRates)
+
+	// Insight: deteksi langganan dari riwayat transaksi
+	app.Get("/insights/subscriptions", middleware.Scope("reports:read"), handlers.GetSubscriptions)
+	app.Post("/insights/subscriptions/:key/confirm", middleware.Scope("transactions:write"), handlers.ConfirmSubscription)
+	app.Post("/insights/subscriptions/:key/dismiss", middleware.Scope("transactions:write"), handlers.DismissSubscription)
+	app.Delete("/insights/subscriptions/:key/dismiss", middleware.Scope("transactions:write"), handlers.UndismissSubscription)
 
 	// Budgets
-	app.Post("/budgets", handlers.CreateBudget)
-	app.Get("/budgets", handlers.GetBudgets)
-	app.Put("/budgets/:id", handlers.UpdateBudget)
-	app.Delete("/budgets/:id", handlers.DeleteBudget)
-	app.Get("/budgets/status", handlers.GetBudgetStatus)
-	app.Get("/budgets/:id/detail", handlers.GetBudgetDetail)
+	app.Post("/budgets", middleware.Scope("budgets:write"), handlers.CreateBudget)
+	app.Get("/budgets", middleware.Scope("budgets:read"), handlers.GetBudgets)
+	app.Put("/budgets/:id", middleware.Scope("budgets:write"), handlers.UpdateBudget)
+	app.Delete("/budgets/:id", middleware.Scope("budgets:write"), handlers.DeleteBudget)
+	app.Get("/budgets/status", middleware.Scope("budgets:read"), handlers.GetBudgetStatus)
+	app.Get("/budgets/:id/detail", middleware.Scope("budgets:read"), handlers.GetBudgetDetail)
 
 	// Notifications
-	app.Post("/notifications", handlers.CreateNotification)
-	app.Get("/notifications", handlers.GetNotifications)
-	app.Get("/notifications/:id", handlers.GetNotificationDetail)
-	app.Delete("/notifications/:id", handlers.DeleteNotification)
+	app.Post("/notifications", middleware.Scope("notifications:write"), handlers.CreateNotification)
+	app.Get("/notifications", middleware.Scope("notifications:read"), handlers.GetNotifications)
+	app.Get("/notifications/:id", middleware.Scope("notifications:read"), handlers.GetNotificationDetail)
+	app.Delete("/notifications/:id", middleware.Scope("notifications:write"), handlers.DeleteNotification)
 
 	// Profile
-	app.Get("/profile", handlers.GetProfile)
-	app.Put("/profile", handlers.UpdateProfile)
-	app.Post("/profile/photo", handlers.UploadPhoto)
+	app.Get("/profile", middleware.Scope("profile:read"), handlers.GetProfile)
+	app.Put("/profile", middleware.Scope("profile:write"), handlers.UpdateProfile)
+	app.Post("/profile/photo", middleware.Scope("profile:write"), handlers.UploadPhoto)
+
+	// Backups
+	app.Post("/backups", middleware.Scope("backups:write"), handlers.CreateBackup)
+	app.Get("/backups", middleware.Scope("backups:read"), handlers.GetBackups)
+	app.Post("/backups/:id/restore", middleware.Scope("backups:write"), handlers.RestoreBackup)
+	app.Get("/backups/schedule", middleware.Scope("backups:read"), handlers.GetBackupSchedule)
+	app.Put("/backups/schedule", middleware.Scope("backups:write"), handlers.PutBackupSchedule)
+	app.Delete("/backups/schedule", middleware.Scope("backups:write"), handlers.DeleteBackupSchedule)
+
+	// Admin
+	admin := app.Group("/admin", middleware.RequireRole(models.RoleAdmin))
+	admin.Get("/users", handlers.AdminListUsers)
+	admin.Get("/users/:id", handlers.AdminGetUser)
+	admin.Put("/users/:id/role", handlers.AdminSetRole)
+	admin.Post("/users/:id/disable", handlers.AdminDisableUser)
+	admin.Post("/users/:id/enable", handlers.AdminEnableUser)
+	admin.Post("/users/:id/force-password-reset", handlers.AdminForcePasswordReset)
+	admin.Get("/stats", handlers.AdminStats)
+	admin.Get("/login-attempts", handlers.GetLoginAttempts)
+	admin.Post("/exchange-rates", handlers.AdminPutExchangeRate)
+	admin.Post("/exchange-rates/import", handlers.AdminImportExchangeRates)
+	admin.Get("/category-templates", handlers.AdminListCategoryTemplates)
+	admin.Post("/category-templates", handlers.AdminCreateCategoryTemplate)
+	admin.Put("/category-templates/:id", handlers.AdminUpdateCategoryTemplate)
+	admin.Delete("/category-templates/:id", handlers.AdminDeleteCategoryTemplate)
 
 	// Ambil PORT dari env, fallback ke 8000
 	port := os.Getenv("PORT")
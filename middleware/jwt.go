@@ -2,13 +2,65 @@
 package middleware
 
 import (
+	"strings"
+	"time"
+
+	"finance/database"
+	"finance/jwtkeys"
+	"finance/models"
+	"finance/utils"
+
 	"github.com/gofiber/fiber/v2"
 	jwtware "github.com/gofiber/jwt/v3"
 )
 
-func JWT(secret string) fiber.Handler {
-	return jwtware.New(jwtware.Config{
-		SigningKey: []byte(secret),
-		ContextKey: "jwt",
+const lastSeenResolution = 5 * time.Minute
+
+// JWT memverifikasi access token dengan kunci yang dipilih lewat header kid.
+// Bearer token berawalan "pat_" diperlakukan sebagai personal access token.
+func JWT(keys *jwtkeys.KeySet) fiber.Handler {
+	verifyJWT := jwtware.New(jwtware.Config{
+		KeyFunc:        keys.Keyfunc,
+		ContextKey:     "jwt",
+		SuccessHandler: activeSession,
 	})
+	return func(c *fiber.Ctx) error {
+		raw, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if ok && strings.HasPrefix(raw, utils.PATPrefix) {
+			return personalAccessToken(c, raw)
+		}
+		return verifyJWT(c)
+	}
+}
+
+func personalAccessToken(c *fiber.Ctx, raw string) error {
+	var pat models.PersonalAccessToken
+	if err := database.DB.Where("token_hash = ?", utils.HashToken(raw)).First(&pat).Error; err != nil ||
+		pat.RevokedAt != nil || (pat.ExpiresAt != nil && time.Now().After(*pat.ExpiresAt)) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid or revoked token"})
+	}
+
+	if pat.LastUsedAt == nil || time.Since(*pat.LastUsedAt) > lastSeenResolution {
+		database.DB.Model(&pat).Update("last_used_at", time.Now())
+	}
+	c.Locals("pat", &pat)
+	return c.Next()
+}
+
+// activeSession menolak token yang sesinya sudah di-revoke (logout / reuse refresh token)
+func activeSession(c *fiber.Ctx) error {
+	sid, err := utils.GetSessionID(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	var sess models.Session
+	if err := database.DB.Select("id", "revoked_at", "last_seen_at").First(&sess, sid).Error; err != nil || sess.RevokedAt != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "session revoked"})
+	}
+
+	// Catat aktivitas terakhir, dibatasi agar tidak menulis ke DB di setiap request
+	if time.Since(sess.LastSeenAt) > lastSeenResolution {
+		database.DB.Model(&sess).Update("last_seen_at", time.Now())
+	}
+	return c.Next()
 }
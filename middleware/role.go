@@ -0,0 +1,22 @@
+// middleware/role.go
+package middleware
+
+import (
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireRole hanya meloloskan access token dengan claim role yang sesuai.
+// Personal access token tidak membawa role sehingga selalu ditolak.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role := utils.GetRole(c)
+		for _, r := range roles {
+			if role == r {
+				return c.Next()
+			}
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+	}
+}
@@ -0,0 +1,22 @@
+// middleware/scope.go
+package middleware
+
+import (
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Scope mengizinkan personal access token dengan scope tertentu memakai route ini.
+// Request dengan JWT login biasa selalu lolos.
+func Scope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if pat := utils.GetPAT(c); pat != nil {
+			if !utils.HasScope(pat.Scopes, scope) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "insufficient scope", "required_scope": scope})
+			}
+			c.Locals("pat_scoped", true)
+		}
+		return c.Next()
+	}
+}
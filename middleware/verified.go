@@ -0,0 +1,32 @@
+// middleware/verified.go
+package middleware
+
+import (
+	"finance/database"
+	"finance/models"
+	"finance/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// VerifiedEmail membatasi akun dengan email belum terverifikasi ke request read-only (GET/HEAD/OPTIONS)
+func VerifiedEmail() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+		uid, err := utils.AuthUserID(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+		}
+		var user models.User
+		if err := database.DB.Select("id", "email_verified_at").First(&user, uid).Error; err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+		}
+		if user.EmailVerifiedAt == nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "email not verified"})
+		}
+		return c.Next()
+	}
+}
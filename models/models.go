@@ -1,7 +1,11 @@
 // models/models.go
 package models
 
-import "time"
+import (
+	"time"
+
+	"finance/money"
+)
 
 type User struct {
 	ID           uint      `gorm:"primaryKey"`
@@ -13,40 +17,128 @@ type User struct {
 	Instagram    string    `json:"Instagram"`
 	CreatedAt    time.Time `gorm:"autoCreateTime"`
 	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+
+	EmailVerifiedAt *time.Time // nil = email belum dikonfirmasi (akun read-only)
+
+	// TOTP 2FA: secret diisi saat enrollment, aktif setelah dikonfirmasi
+	TOTPSecret   string `gorm:"size:64" json:"-"`
+	TOTPEnabled  bool   `gorm:"not null;default:false"`
+	TOTPLastStep int64  `json:"-"`
+
+	Role                  string `gorm:"size:20;not null;default:user"` // "user" atau "admin"
+	DisabledAt            *time.Time
+	PasswordResetRequired bool       `gorm:"not null;default:false"`
+	DeletionScheduledAt   *time.Time // akun dan seluruh datanya dihapus permanen setelah waktu ini
+
+	BaseCurrency string `gorm:"size:3;not null;default:IDR"` // laporan dan budget dikonversi ke mata uang ini
 }
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type Category struct {
+	ID         uint       `gorm:"primaryKey"`
+	UserID     uint       `gorm:"not null;index"`
+	Name       string     `gorm:"size:100;not null"`
+	Type       string     `gorm:"size:20;not null"` // "income" or "expense"
+	ParentID   *uint      `gorm:"index"`            // nil = kategori utama; tipe selalu sama dengan induk
+	ArchivedAt *time.Time // disembunyikan dari pilihan kategori, tetap muncul di laporan
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `gorm:"autoUpdateTime"`
+}
+
+// CategoryTemplate adalah kategori bawaan per bahasa (locale) yang disalin ke akun baru.
+// Template hanya dua tingkat: ParentID menunjuk template utama dengan locale dan tipe yang sama.
+type CategoryTemplate struct {
 	ID        uint      `gorm:"primaryKey"`
-	UserID    uint      `gorm:"not null;index"`
+	Locale    string    `gorm:"size:10;not null;index"` // "id", "en", ...
 	Name      string    `gorm:"size:100;not null"`
 	Type      string    `gorm:"size:20;not null"` // "income" or "expense"
+	ParentID  *uint     `gorm:"index"`
 	CreatedAt time.Time `gorm:"autoCreateTime"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime"`
 }
 
 type Transaction struct {
-	ID         uint      `gorm:"primaryKey"`
-	UserID     uint      `gorm:"not null;index"`
-	CategoryID uint      `gorm:"not null;index"`
-	Amount     float64   `gorm:"type:decimal(15,2);not null"`
-	Date       time.Time `gorm:"not null;index"`
-	Note       string    `gorm:"type:text"`
-	CreatedAt  time.Time `gorm:"autoCreateTime"`
-	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+	ID         uint         `gorm:"primaryKey"`
+	UserID     uint         `gorm:"not null;index;index:idx_transactions_user_currency,priority:1"`
+	CategoryID uint         `gorm:"not null;index"`
+	Amount     money.Amount `gorm:"type:decimal(15,2);not null"`
+	Date       time.Time    `gorm:"not null;index"`
+	Note       string       `gorm:"type:text"`
+	CreatedAt  time.Time    `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time    `gorm:"autoUpdateTime"`
+
+	AccountID *uint  `gorm:"index"` // nil untuk transaksi lama yang belum ditautkan ke akun
+	Currency  string `gorm:"size:3;not null;default:IDR;index:idx_transactions_user_currency,priority:2"`
+
+	// Leg transfer antar akun: category_id 0, tidak dihitung sebagai income/expense
+	TransferID  *uint  `gorm:"index"`
+	TransferLeg string `gorm:"size:3"` // "out" atau "in"
+
+	RecurringID *uint `gorm:"index"` // diisi bila dibuat dari template recurring
+}
+
+// TransactionSplit adalah satu baris kategori dari transaksi yang di-split.
+// Jumlah semua baris sama dengan Transaction.Amount; Transaction.CategoryID = kategori baris pertama.
+type TransactionSplit struct {
+	ID            uint         `gorm:"primaryKey"`
+	TransactionID uint         `gorm:"not null;index"`
+	UserID        uint         `gorm:"not null;index"`
+	CategoryID    uint         `gorm:"not null;index"`
+	Amount        money.Amount `gorm:"type:decimal(15,2);not null"`
+	Note          string       `gorm:"type:text"`
+}
+
+// Transfer memindahkan uang antar akun; dicatat sebagai dua transaksi (leg keluar dan masuk)
+type Transfer struct {
+	ID            uint         `gorm:"primaryKey"`
+	UserID        uint         `gorm:"not null;index"`
+	FromAccountID uint         `gorm:"not null;index"`
+	ToAccountID   uint         `gorm:"not null;index"`
+	Amount        money.Amount `gorm:"type:decimal(15,2);not null"`
+	Date          time.Time    `gorm:"not null;index"`
+	Note          string       `gorm:"type:text"`
+	Currency      string       `gorm:"size:3;not null;default:IDR"` // sama dengan mata uang kedua akun
+	CreatedAt     time.Time    `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time    `gorm:"autoUpdateTime"`
+}
+
+const (
+	TransferOut = "out"
+	TransferIn  = "in"
+)
+
+// Account adalah tempat uang disimpan: kas, rekening bank, e-wallet, dll.
+type Account struct {
+	ID             uint         `gorm:"primaryKey"`
+	UserID         uint         `gorm:"not null;index"`
+	Name           string       `gorm:"size:100;not null"`
+	Type           string       `gorm:"size:20;not null"` // cash, bank, ewallet, credit_card, other
+	OpeningBalance money.Amount `gorm:"type:decimal(15,2);not null"`
+	Currency       string       `gorm:"size:3;not null;default:IDR"`
+	CreatedAt      time.Time    `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time    `gorm:"autoUpdateTime"`
+
+	Balance money.Amount `gorm:"-" json:"Balance"`
 }
 
+var AccountTypes = []string{"cash", "bank", "ewallet", "credit_card", "other"}
+
 type Budget struct {
-	ID          uint      `gorm:"primaryKey"`
-	UserID      uint      `gorm:"not null;index"`
-	CategoryID  uint      `gorm:"not null;index"`
-	LimitAmount float64   `gorm:"type:decimal(15,2);not null"`
-	StartDate   time.Time `gorm:"not null"`
-	EndDate     time.Time `gorm:"not null"`
-	CreatedAt   time.Time `gorm:"autoCreateTime"`
-	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+	ID          uint         `gorm:"primaryKey"`
+	UserID      uint         `gorm:"not null;index"`
+	CategoryID  uint         `gorm:"not null;index"`
+	LimitAmount money.Amount `gorm:"type:decimal(15,2);not null"`
+	StartDate   time.Time    `gorm:"not null"`
+	EndDate     time.Time    `gorm:"not null"`
+	CreatedAt   time.Time    `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time    `gorm:"autoUpdateTime"`
 
-	SpentAmount float64 `gorm:"-" json:"SpentAmount"`
-	Status      string  `gorm:"-" json:"Status"`
+	SpentAmount money.Amount `gorm:"-" json:"SpentAmount"`
+	Status      string       `gorm:"-" json:"Status"`
 }
 
 type Backup struct {
@@ -54,6 +146,10 @@ type Backup struct {
 	UserID    uint      `gorm:"not null;index"`
 	BackupURL string    `gorm:"type:text;not null"`
 	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	Version   int    `gorm:"not null;default:1"` // versi format arsip
+	SizeBytes int64  `gorm:"not null;default:0"`
+	Kind      string `gorm:"size:20;not null;default:manual"` // "manual" atau "scheduled"
 }
 
 type Notification struct {
@@ -63,3 +159,232 @@ type Notification struct {
 	Message   string    `json:"message"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// Session mewakili satu login (satu keluarga refresh token)
+type Session struct {
+	ID         uint   `gorm:"primaryKey"`
+	UserID     uint   `gorm:"not null;index"`
+	Device     string `gorm:"size:100"`
+	UserAgent  string `gorm:"size:255"`
+	IP         string `gorm:"size:45"`
+	LastSeenAt time.Time
+	ExpiresAt  time.Time `gorm:"not null"`
+	RevokedAt  *time.Time
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+}
+
+// RefreshToken disimpan dalam bentuk hash; setiap token hanya boleh dipakai sekali
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	SessionID uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// PasswordResetToken hanya disimpan hash-nya, sekali pakai dan punya masa berlaku
+type PasswordResetToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// EmailVerificationToken mengikat token ke alamat email yang sedang diverifikasi
+type EmailVerificationToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	Email     string    `gorm:"size:150;not null"`
+	TokenHash string    `gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// RecoveryCode adalah kode cadangan 2FA sekali pakai (disimpan hash-nya)
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"size:64;not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// UserIdentity menautkan identitas eksternal (provider OIDC + sub) ke satu User
+type UserIdentity struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	Provider  string    `gorm:"size:50;not null;uniqueIndex:idx_identity_provider_subject"`
+	Subject   string    `gorm:"size:255;not null;uniqueIndex:idx_identity_provider_subject"`
+	Email     string    `gorm:"size:150"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// LoginAttempt adalah counter login gagal per akun ("account:<email>") atau per IP ("ip:<addr>")
+type LoginAttempt struct {
+	ID            uint   `gorm:"primaryKey"`
+	Key           string `gorm:"column:attempt_key;size:200;uniqueIndex;not null"`
+	Failures      int    `gorm:"not null;default:0"`
+	Lockouts      int    `gorm:"not null;default:0"`
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+// PersonalAccessToken untuk script/integrasi; hanya hash yang disimpan, Scopes dipisah spasi
+type PersonalAccessToken struct {
+	ID         uint   `gorm:"primaryKey"`
+	UserID     uint   `gorm:"not null;index"`
+	Name       string `gorm:"size:100;not null"`
+	Prefix     string `gorm:"size:16;not null"`
+	TokenHash  string `gorm:"size:64;uniqueIndex;not null"`
+	Scopes     string `gorm:"size:500;not null"`
+	LastUsedAt *time.Time
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+// BackupSchedule adalah jadwal backup otomatis per user (satu jadwal per user)
+type BackupSchedule struct {
+	ID          uint      `gorm:"primaryKey"`
+	UserID      uint      `gorm:"not null;uniqueIndex"`
+	Frequency   string    `gorm:"size:10;not null"` // "daily" atau "weekly"
+	Weekday     int       `gorm:"not null"`         // 0 = Minggu, hanya untuk weekly
+	Hour        int       `gorm:"not null"`
+	Timezone    string    `gorm:"size:64;not null;default:Asia/Jakarta"`
+	KeepRecent  int       `gorm:"not null"` // jumlah backup terjadwal terbaru yang disimpan
+	KeepMonthly int       `gorm:"not null"` // ditambah satu backup per bulan untuk N bulan terakhir
+	Enabled     bool      `gorm:"not null"`
+	NextRunAt   time.Time `gorm:"not null;index"`
+	LastRunAt   *time.Time
+	LastError   string    `gorm:"type:text"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
+
+const (
+	BackupManual    = "manual"
+	BackupScheduled = "scheduled"
+)
+
+// ExchangeRate: 1 unit Base = Rate unit Quote pada tanggal Date.
+// Konversi arah sebaliknya memakai 1/Rate.
+type ExchangeRate struct {
+	ID        uint       `gorm:"primaryKey"`
+	Base      string     `gorm:"size:3;not null;uniqueIndex:idx_rate_pair_date"`
+	Quote     string     `gorm:"size:3;not null;uniqueIndex:idx_rate_pair_date"`
+	Date      time.Time  `gorm:"type:date;not null;uniqueIndex:idx_rate_pair_date"`
+	Rate      money.Rate `gorm:"type:numeric(24,10);not null"`
+	Source    string     `gorm:"size:50"` // "manual", "file", dst.
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+}
+
+// Recurrence adalah aturan jadwal bergaya RRULE: Frequency + Interval, MonthDay untuk bulanan,
+// batas Until dan/atau Count. Dipakai template recurring dan tagihan.
+type Recurrence struct {
+	Frequency string     `gorm:"size:10;not null"`                // "daily", "weekly" atau "monthly"
+	Interval  int        `gorm:"column:repeat_interval;not null"` // setiap N hari/minggu/bulan
+	MonthDay  int        `gorm:"not null"`                        // monthly: 1..31 (dipotong ke akhir bulan), -1 = akhir bulan, 0 = tanggal StartDate
+	StartDate time.Time  `gorm:"not null"`                        // kemunculan pertama; jamnya dipakai untuk semua kemunculan
+	Until     *time.Time `gorm:"type:date"`                       // kemunculan terakhir paling lambat tanggal ini
+	Count     int        `gorm:"not null"`                        // jumlah kemunculan maksimal, 0 = tanpa batas
+}
+
+// RecurringTransaction adalah template transaksi berulang (gaji, sewa, langganan)
+type RecurringTransaction struct {
+	ID         uint         `gorm:"primaryKey"`
+	UserID     uint         `gorm:"not null;index"`
+	CategoryID uint         `gorm:"not null;index"`
+	AccountID  *uint        `gorm:"index"`
+	Amount     money.Amount `gorm:"type:decimal(15,2);not null"`
+	Currency   string       `gorm:"size:3;not null"`
+	Note       string       `gorm:"type:text"`
+
+	Recurrence
+
+	// State worker: kemunculan ke-NextIndex (mulai 0) dibuat pada NextRunAt; nil = seri selesai
+	NextIndex int        `gorm:"not null"`
+	NextRunAt *time.Time `gorm:"index"`
+	LastError string     `gorm:"type:text"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime"`
+}
+
+// RecurringOccurrence mencatat satu kemunculan template: yang sudah dibuat, di-skip,
+// gagal, atau diubah khusus untuk tanggal itu (pending) sebelum jatuh tempo.
+type RecurringOccurrence struct {
+	ID            uint      `gorm:"primaryKey"`
+	RecurringID   uint      `gorm:"not null;uniqueIndex:idx_recurring_occurrence"`
+	UserID        uint      `gorm:"not null;index"`
+	Date          time.Time `gorm:"type:date;not null;uniqueIndex:idx_recurring_occurrence"` // tanggal terjadwal
+	Status        string    `gorm:"size:10;not null"`
+	TransactionID *uint
+
+	// Perubahan untuk kemunculan ini saja; nil = ikut template
+	CategoryID *uint
+	Amount     *money.Amount `gorm:"type:decimal(15,2)"`
+	Note       *string       `gorm:"type:text"`
+
+	Error     string    `gorm:"type:text"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+const (
+	OccurrencePending = "pending"
+	OccurrenceCreated = "created"
+	OccurrenceSkipped = "skipped"
+	OccurrenceFailed  = "failed"
+)
+
+// Bill adalah tagihan yang jatuh tempo berkala (sewa, kartu kredit, listrik).
+// Jadwal jatuh tempo memakai aturan yang sama dengan transaksi berulang.
+type Bill struct {
+	ID         uint         `gorm:"primaryKey"`
+	UserID     uint         `gorm:"not null;index"`
+	Payee      string       `gorm:"size:100;not null"`
+	Amount     money.Amount `gorm:"type:decimal(15,2);not null"`
+	Currency   string       `gorm:"size:3;not null"`
+	CategoryID uint         `gorm:"not null;index"` // kategori transaksi saat dibayar
+	AccountID  *uint        `gorm:"index"`          // akun pembayar
+	Note       string       `gorm:"type:text"`
+	AutoPay    bool         `gorm:"not null"` // dibayar otomatis pada tanggal jatuh tempo
+	RemindDays int          `gorm:"not null"` // pengingat N hari sebelum jatuh tempo, 0 = hanya saat jatuh tempo
+
+	Recurrence
+
+	// State: jatuh tempo ke-NextIndex (mulai 0) adalah yang tertua belum dibayar; nil = selesai.
+	// Pengingat/overdue untuk jatuh tempo ke-n sudah dikirim bila n < RemindedIndex/OverdueIndex.
+	NextIndex     int        `gorm:"not null"`
+	NextDueAt     *time.Time `gorm:"index"`
+	RemindedIndex int        `gorm:"not null"`
+	OverdueIndex  int        `gorm:"not null"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `gorm:"autoUpdateTime"`
+}
+
+// BillPayment mencatat pembayaran satu jatuh tempo tagihan
+type BillPayment struct {
+	ID            uint      `gorm:"primaryKey"`
+	BillID        uint      `gorm:"not null;uniqueIndex:idx_bill_payment_due"`
+	UserID        uint      `gorm:"not null;index"`
+	DueDate       time.Time `gorm:"type:date;not null;uniqueIndex:idx_bill_payment_due"`
+	TransactionID *uint
+	AutoPaid      bool      `gorm:"not null"`
+	PaidAt        time.Time `gorm:"not null"`
+}
+
+// SubscriptionDismissal menandai pola langganan hasil deteksi yang ditolak user,
+// agar tidak muncul lagi di GET /insights/subscriptions
+type SubscriptionDismissal struct {
+	ID          uint      `gorm:"primaryKey"`
+	UserID      uint      `gorm:"not null;uniqueIndex:idx_subscription_dismissal"`
+	Key         string    `gorm:"size:32;not null;uniqueIndex:idx_subscription_dismissal"`
+	Pattern     string    `gorm:"type:text"`
+	DismissedAt time.Time `gorm:"not null"`
+}
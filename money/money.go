@@ -0,0 +1,163 @@
+// money/money.go
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Amount adalah nilai uang dalam satuan terkecil (sen, 1/100). Semua penjumlahan
+// dilakukan dengan integer sehingga tidak ada selisih pembulatan seperti float64.
+//
+// Di JSON ditulis sebagai angka desimal dengan dua digit ("15000.50"), di database
+// disimpan ke kolom decimal(15,2). Parsing selalu dari teks, tidak pernah lewat float.
+type Amount int64
+
+const (
+	scale     = 100
+	maxDigits = 16 // batas digit sebelum koma agar muat di int64
+
+	// Max adalah nilai terbesar yang muat di kolom decimal(15,2)
+	Max Amount = 999999999999999
+)
+
+var (
+	ErrInvalid   = errors.New("invalid amount")
+	ErrPrecision = errors.New("amount must have at most 2 decimal places")
+	ErrRange     = errors.New("amount out of range")
+)
+
+// FromMinor membuat Amount dari satuan terkecil (mis. 150050 = 1500.50)
+func FromMinor(v int64) Amount { return Amount(v) }
+
+// FromInt membuat Amount dari nilai utuh (mis. 1500 = 1500.00)
+func FromInt(v int64) Amount { return Amount(v * scale) }
+
+// Minor mengembalikan nilai dalam satuan terkecil
+func (a Amount) Minor() int64 { return int64(a) }
+
+// Float64 hanya untuk tampilan/persentase, jangan dipakai untuk menjumlah
+func (a Amount) Float64() float64 { return float64(a) / scale }
+
+// Parse membaca teks desimal seperti "1500", "-1500.5" atau "1500.50"
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	neg := false
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	whole, frac, hasDot := strings.Cut(s, ".")
+	if whole == "" && frac == "" || !digitsOnly(whole) || !digitsOnly(frac) || (hasDot && frac == "") {
+		return 0, ErrInvalid
+	}
+	// nol di belakang koma tidak menambah presisi ("1.500" = "1.50")
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > 2 {
+		return 0, ErrPrecision
+	}
+	whole = strings.TrimLeft(whole, "0")
+	if len(whole) > maxDigits {
+		return 0, ErrRange
+	}
+	var w, f int64
+	if whole != "" {
+		w, _ = strconv.ParseInt(whole, 10, 64)
+	}
+	if frac != "" {
+		f, _ = strconv.ParseInt((frac + "0")[:2], 10, 64)
+	}
+	v := w*scale + f
+	if neg {
+		v = -v
+	}
+	return Amount(v), nil
+}
+
+func digitsOnly(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String selalu menulis dua digit desimal, mis. "-1500.05"
+func (a Amount) String() string {
+	v := int64(a)
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		if v == math.MinInt64 {
+			return "-92233720368547758.08"
+		}
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/scale, v%scale)
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON menerima angka (15000.5) maupun string ("15000.50")
+func (a *Amount) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	s = strings.Trim(s, `"`)
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Scan membaca nilai decimal dari database (driver Postgres mengirim teks)
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	case int64:
+		*a = FromInt(v)
+	case float64:
+		// hanya terjadi bila kolom bukan decimal; dibulatkan ke sen terdekat
+		*a = Amount(math.Round(v * scale))
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+	return nil
+}
+
+func (a *Amount) scanString(s string) error {
+	v, err := Parse(s)
+	if err != nil {
+		return fmt.Errorf("money: cannot scan %q: %w", s, err)
+	}
+	*a = v
+	return nil
+}
+
+// Value menulis ke database sebagai teks desimal agar tidak lewat float
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// Percent mengembalikan a / total * 100 (untuk tampilan)
+func (a Amount) Percent(total Amount) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(a) / float64(total) * 100
+}
@@ -0,0 +1,198 @@
+// money/money_test.go
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+		err  error
+	}{
+		{"0", 0, nil},
+		{"0.1", 10, nil},
+		{"-.5", -50, nil},
+		{"+.5", 50, nil},
+		{"1.500", 150, nil}, // nol di belakang tidak menambah presisi
+		{"1500", 150000, nil},
+		{"-1500.05", -150005, nil},
+		{"  42.42  ", 4242, nil},
+		{"007.10", 710, nil},
+		{"1.005", 0, ErrPrecision},
+		{"0.001", 0, ErrPrecision},
+		{"9999999999999999", 999999999999999900, nil}, // 16 digit: masih muat di int64
+		{"9999999999999999.99", 999999999999999999, nil},
+		{"10000000000000000", 0, ErrRange}, // 17 digit
+		{"-99999999999999999", 0, ErrRange},
+		{"", 0, ErrInvalid},
+		{".", 0, ErrInvalid},
+		{"1.", 0, ErrInvalid},
+		{"1e3", 0, ErrInvalid},
+		{"1,5", 0, ErrInvalid},
+		{"--1", 0, ErrInvalid},
+		{"abc", 0, ErrInvalid},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Parse(%q) error = %v, want %v", tt.in, err, tt.err)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("Parse(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseAboveColumnMax(t *testing.T) {
+	// 16 digit lolos Parse tapi melebihi decimal(15,2); validasi handler memakai Max
+	a, err := Parse("9999999999999999")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a <= Max {
+		t.Errorf("%s should be above Max %s", a, Max)
+	}
+	if m, _ := Parse("9999999999999.99"); m != Max {
+		t.Errorf("Parse(max column value) = %d, want %d", m, Max)
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   Amount
+		want string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{-5, "-0.05"},
+		{150050, "1500.50"},
+		{-150005, "-1500.05"},
+		{Max, "9999999999999.99"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Amount(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	for _, a := range []Amount{0, 1, -1, 10, 99, 150050, -150005, Max, -Max} {
+		b, err := json.Marshal(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var back Amount
+		if err := json.Unmarshal(b, &back); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", b, err)
+		}
+		if back != a {
+			t.Errorf("round-trip %d -> %s -> %d", int64(a), b, int64(back))
+		}
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	var body struct {
+		Amount Amount  `json:"amount"`
+		Limit  *Amount `json:"limit"`
+	}
+	if err := json.Unmarshal([]byte(`{"amount": 15000.5, "limit": "0.10"}`), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Amount != 1500050 || body.Limit == nil || *body.Limit != 10 {
+		t.Errorf("got amount=%d limit=%v", body.Amount, body.Limit)
+	}
+	if err := json.Unmarshal([]byte(`{"amount": 0.125}`), &body); !errors.Is(err, ErrPrecision) {
+		t.Errorf("expected ErrPrecision, got %v", err)
+	}
+	body.Amount = 7
+	if err := json.Unmarshal([]byte(`{"amount": null}`), &body); err != nil || body.Amount != 7 {
+		t.Errorf("null should leave value untouched, got %d (%v)", body.Amount, err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		src  interface{}
+		want Amount
+	}{
+		{[]byte("1500.50"), 150050},       // decimal(15,2)
+		{[]byte("-0.05"), -5},             // negatif
+		{[]byte("0.00"), 0},               // COALESCE(SUM(..),0) pada decimal
+		{[]byte("0"), 0},                  // COALESCE(..,0) tanpa skala
+		{[]byte("12345.600000"), 1234560}, // hasil konversi kurs (numeric dengan skala besar)
+		{"99.99", 9999},
+		{int64(12), 1200},
+		{0.1 + 0.2, 30}, // float dibulatkan ke sen terdekat
+		{nil, 0},
+	}
+	for _, tt := range tests {
+		a := Amount(99)
+		if err := a.Scan(tt.src); err != nil {
+			t.Errorf("Scan(%v): %v", tt.src, err)
+			continue
+		}
+		if a != tt.want {
+			t.Errorf("Scan(%v) = %d, want %d", tt.src, a, tt.want)
+		}
+	}
+
+	var a Amount
+	if err := a.Scan([]byte("1.005")); !errors.Is(err, ErrPrecision) {
+		t.Errorf("Scan of 3 decimals: expected ErrPrecision, got %v", err)
+	}
+	if err := a.Scan(true); err == nil {
+		t.Error("Scan(bool) should fail")
+	}
+}
+
+func TestValueRoundTrip(t *testing.T) {
+	for _, a := range []Amount{0, 10, -150005, Max} {
+		v, err := a.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var back Amount
+		if err := back.Scan(v); err != nil || back != a {
+			t.Errorf("Value/Scan round-trip %d -> %v -> %d (%v)", int64(a), v, int64(back), err)
+		}
+	}
+}
+
+func TestNoRoundingDrift(t *testing.T) {
+	dime, err := Parse("0.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total Amount
+	for i := 0; i < 10000; i++ {
+		total += dime
+	}
+	if total != FromInt(1000) || total.String() != "1000.00" {
+		t.Errorf("10000 x 0.10 = %s, want 1000.00", total)
+	}
+
+	// pembanding: float64 memang bergeser
+	f := 0.0
+	for i := 0; i < 10000; i++ {
+		f += 0.1
+	}
+	if f == 1000 {
+		t.Log("float64 sum happened to be exact")
+	}
+}
+
+func TestPercent(t *testing.T) {
+	if got := FromInt(80).Percent(FromInt(100)); got != 80 {
+		t.Errorf("Percent = %v, want 80", got)
+	}
+	if got := FromInt(5).Percent(0); got != 0 {
+		t.Errorf("Percent with zero total = %v, want 0", got)
+	}
+}
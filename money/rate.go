@@ -0,0 +1,127 @@
+// money/rate.go
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Rate adalah kurs: 1 unit mata uang base = Rate unit quote. Disimpan sebagai teks desimal
+// kanonik (kolom numeric(24,10)) dan dihitung dengan big.Rat, tidak pernah lewat float64.
+// Nilai kosong ("") berarti belum diisi.
+type Rate string
+
+const (
+	rateScale     = 10 // digit di belakang koma, sesuai numeric(24,10)
+	rateMaxDigits = 14 // digit sebelum koma
+)
+
+var ErrInvalidRate = errors.New("rate must be a positive decimal with at most 14 digits before and 10 after the point")
+
+// ParseRate membaca teks desimal positif seperti "15800", "0.0000632911" atau "1.085"
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	whole, frac, hasDot := strings.Cut(s, ".")
+	if whole == "" && frac == "" || !digitsOnly(whole) || !digitsOnly(frac) || (hasDot && frac == "") {
+		return "", ErrInvalidRate
+	}
+	frac = strings.TrimRight(frac, "0")
+	whole = strings.TrimLeft(whole, "0")
+	if len(frac) > rateScale || len(whole) > rateMaxDigits || whole == "" && frac == "" {
+		return "", ErrInvalidRate
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	if frac == "" {
+		return Rate(whole), nil
+	}
+	return Rate(whole + "." + frac), nil
+}
+
+// rateFromRat membulatkan x (positif) ke rateScale digit, setengah ke atas
+func rateFromRat(x *big.Rat) (Rate, error) {
+	if x.Sign() <= 0 {
+		return "", ErrInvalidRate
+	}
+	scaled := new(big.Int).Mul(x.Num(), new(big.Int).Exp(big.NewInt(10), big.NewInt(rateScale), nil))
+	q, rem := new(big.Int).QuoRem(scaled, x.Denom(), new(big.Int))
+	if rem.Lsh(rem, 1).Cmp(x.Denom()) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	digits := fmt.Sprintf("%0*s", rateScale+1, q.String())
+	cut := len(digits) - rateScale
+	return ParseRate(digits[:cut] + "." + digits[cut:])
+}
+
+// Rat mengembalikan kurs sebagai bilangan rasional (nil bila kosong)
+func (r Rate) Rat() *big.Rat {
+	x, ok := new(big.Rat).SetString(string(r))
+	if !ok {
+		return nil
+	}
+	return x
+}
+
+// Inverse mengembalikan 1/r (kurs pasangan terbalik), dibulatkan ke 10 digit desimal
+func (r Rate) Inverse() (Rate, error) {
+	x := r.Rat()
+	if x == nil || x.Sign() <= 0 {
+		return "", ErrInvalidRate
+	}
+	return rateFromRat(x.Inv(x))
+}
+
+func (r Rate) IsZero() bool { return r == "" }
+
+func (r Rate) String() string { return string(r) }
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	if r == "" {
+		return []byte("null"), nil
+	}
+	return []byte(r), nil
+}
+
+// UnmarshalJSON menerima angka (15800.5) maupun string ("15800.5")
+func (r *Rate) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	v, err := ParseRate(strings.Trim(s, `"`))
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+// Scan membaca kolom numeric (driver Postgres mengirim teks, mis. "15800.0000000000")
+func (r *Rate) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*r = ""
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("money: cannot scan %T into rate", src)
+	}
+	v, err := ParseRate(s)
+	if err != nil {
+		return fmt.Errorf("money: cannot scan rate %q: %w", s, err)
+	}
+	*r = v
+	return nil
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return string(r), nil
+}
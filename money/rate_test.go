@@ -0,0 +1,76 @@
+// money/rate_test.go
+package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Rate
+		ok   bool
+	}{
+		{"15800", "15800", true},
+		{"015800.500", "15800.5", true},
+		{"0.0000632911", "0.0000632911", true},
+		{".5", "0.5", true},
+		{"1.0000000000", "1", true},
+		{"99999999999999.9999999999", "99999999999999.9999999999", true},
+		{"0.00000000001", "", false}, // 11 digit desimal
+		{"100000000000000", "", false},
+		{"0", "", false},
+		{"0.000", "", false},
+		{"-1", "", false},
+		{"1e3", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseRate(%q) = %q, %v; want %q ok=%v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRateInverse(t *testing.T) {
+	tests := []struct {
+		in, want Rate
+	}{
+		{"2", "0.5"},
+		{"15800", "0.0000632911"}, // 0.00006329113924... dibulatkan
+		{"3", "0.3333333333"},
+		{"1.5", "0.6666666667"}, // setengah ke atas
+		{"0.0000632911", "15800.0097960061"},
+	}
+	for _, tt := range tests {
+		got, err := tt.in.Inverse()
+		if err != nil || got != tt.want {
+			t.Errorf("Rate(%s).Inverse() = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestRateJSONAndScan(t *testing.T) {
+	var body struct {
+		Rate Rate `json:"rate"`
+	}
+	if err := json.Unmarshal([]byte(`{"rate": 15800.25}`), &body); err != nil || body.Rate != "15800.25" {
+		t.Errorf("number: %q, %v", body.Rate, err)
+	}
+	if err := json.Unmarshal([]byte(`{"rate": "0.000063"}`), &body); err != nil || body.Rate != "0.000063" {
+		t.Errorf("string: %q, %v", body.Rate, err)
+	}
+	if b, _ := json.Marshal(body); string(b) != `{"rate":0.000063}` {
+		t.Errorf("Marshal = %s", b)
+	}
+
+	var r Rate
+	if err := r.Scan([]byte("15800.0000000000")); err != nil || r != "15800" {
+		t.Errorf("Scan numeric(24,10) = %q, %v", r, err)
+	}
+	if err := r.Scan(1.5); err == nil {
+		t.Error("Scan(float64) should fail")
+	}
+}
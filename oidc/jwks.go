@@ -0,0 +1,162 @@
+// oidc/jwks.go
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJWKSTTL = time.Hour
+	// jeda minimal antar fetch agar kid asing tidak memicu request berulang
+	minRefetchInterval = time.Minute
+)
+
+// JWKSCache menyimpan kunci publik penerbit token di memori sehingga
+// verifikasi dilakukan secara lokal, tanpa request jaringan per login.
+type JWKSCache struct {
+	URL    string
+	Client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	expires   time.Time
+	lastFetch time.Time
+}
+
+func NewJWKSCache(url string) *JWKSCache {
+	return &JWKSCache{URL: url, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Key mengembalikan kunci publik untuk kid, mengambil ulang JWKS bila cache
+// kedaluwarsa atau kid belum dikenal (mis. penerbit baru merotasi kunci).
+func (c *JWKSCache) Key(kid string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	key, ok := c.keys[kid]
+	stale := now.After(c.expires)
+	if ok && !stale {
+		return key, nil
+	}
+	if stale || now.Sub(c.lastFetch) >= minRefetchInterval {
+		if err := c.fetch(now); err != nil {
+			// Kunci lama masih bisa dipakai bila endpoint JWKS sedang tidak bisa dihubungi
+			if ok {
+				return key, nil
+			}
+			return nil, err
+		}
+		if key, ok = c.keys[kid]; ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown kid %q", kid)
+}
+
+func (c *JWKSCache) fetch(now time.Time) error {
+	c.lastFetch = now
+	resp, err := c.Client.Get(c.URL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jwks fetch: status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return err
+	}
+	keys := map[string]interface{}{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	if len(keys) == 0 {
+		return errors.New("jwks fetch: no usable keys")
+	}
+	c.keys = keys
+	c.expires = now.Add(maxAge(resp.Header.Get("Cache-Control")))
+	return nil
+}
+
+// maxAge membaca max-age dari header Cache-Control (Google memakainya untuk jadwal rotasi)
+func maxAge(cacheControl string) time.Duration {
+	for _, part := range strings.Split(cacheControl, ",") {
+		part = strings.TrimSpace(part)
+		if v, ok := strings.CutPrefix(part, "max-age="); ok {
+			if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+				return time.Duration(secs) * time.Second
+			}
+		}
+	}
+	return defaultJWKSTTL
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported kty %q", k.Kty)
+}
@@ -0,0 +1,168 @@
+// oidc/provider.go
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider adalah satu penerbit OpenID Connect (Google, Apple, Microsoft, Keycloak, ...)
+type Provider struct {
+	Name      string   `json:"name"`
+	Issuer    string   `json:"issuer"`
+	ClientIDs []string `json:"client_ids"`
+	// DiscoveryURL default: <issuer>/.well-known/openid-configuration
+	DiscoveryURL string `json:"discovery_url"`
+	// JWKSURL opsional; bila kosong diambil dari dokumen discovery
+	JWKSURL string `json:"jwks_url"`
+	// ExtraIssuers untuk penerbit yang memakai lebih dari satu nilai iss (mis. Google)
+	ExtraIssuers []string `json:"extra_issuers"`
+
+	mu       sync.Mutex
+	verifier *Verifier
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]*Provider{}
+)
+
+// LoadProviders membaca registry provider dari OIDC_PROVIDERS_FILE (array JSON Provider).
+// Google tetap tersedia lewat GOOGLE_CLIENT_IDS/GOOGLE_JWKS_URL bila tidak didefinisikan di file.
+func LoadProviders() {
+	list := []*Provider{}
+	if path := os.Getenv("OIDC_PROVIDERS_FILE"); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatal("Failed to read OIDC providers:", err)
+		}
+		if err := json.Unmarshal(raw, &list); err != nil {
+			log.Fatal("Failed to parse OIDC providers:", err)
+		}
+	}
+
+	reg := map[string]*Provider{}
+	for _, p := range list {
+		if p.Name == "" || p.Issuer == "" || len(p.ClientIDs) == 0 {
+			log.Fatalf("Invalid OIDC provider %q: name, issuer and client_ids are required", p.Name)
+		}
+		reg[strings.ToLower(p.Name)] = p
+	}
+	if _, ok := reg["google"]; !ok {
+		reg["google"] = googleFromEnv()
+	}
+
+	providersMu.Lock()
+	providers = reg
+	providersMu.Unlock()
+}
+
+func googleFromEnv() *Provider {
+	var clientIDs []string
+	for _, id := range strings.Split(os.Getenv("GOOGLE_CLIENT_IDS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			clientIDs = append(clientIDs, id)
+		}
+	}
+	jwksURL := os.Getenv("GOOGLE_JWKS_URL")
+	if jwksURL == "" {
+		jwksURL = "https://www.googleapis.com/oauth2/v3/certs"
+	}
+	return &Provider{
+		Name:         "google",
+		Issuer:       "https://accounts.google.com",
+		ExtraIssuers: []string{"accounts.google.com"},
+		ClientIDs:    clientIDs,
+		JWKSURL:      jwksURL,
+	}
+}
+
+var ErrUnknownProvider = errors.New("unknown provider")
+
+// Get mengembalikan provider berdasarkan nama (case-insensitive)
+func Get(name string) (*Provider, error) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	p, ok := providers[strings.ToLower(name)]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// Names mengembalikan nama semua provider yang terdaftar
+func Names() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Verify memverifikasi ID token dari provider ini
+func (p *Provider) Verify(raw string) (*Claims, error) {
+	p.mu.Lock()
+	if p.verifier == nil {
+		// Discovery gagal tidak di-cache, dicoba lagi pada login berikutnya
+		v, err := p.newVerifier()
+		if err != nil {
+			p.mu.Unlock()
+			return nil, err
+		}
+		p.verifier = v
+	}
+	v := p.verifier
+	p.mu.Unlock()
+	return v.Verify(raw)
+}
+
+func (p *Provider) newVerifier() (*Verifier, error) {
+	jwksURL := p.JWKSURL
+	if jwksURL == "" {
+		var err error
+		if jwksURL, err = p.discoverJWKS(); err != nil {
+			return nil, err
+		}
+	}
+	return &Verifier{
+		Issuers:   append([]string{p.Issuer}, p.ExtraIssuers...),
+		ClientIDs: p.ClientIDs,
+		Keys:      NewJWKSCache(jwksURL),
+	}, nil
+}
+
+func (p *Provider) discoverJWKS() (string, error) {
+	url := p.DiscoveryURL
+	if url == "" {
+		url = strings.TrimSuffix(p.Issuer, "/") + "/.well-known/openid-configuration"
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("discovery %s: status %d", p.Name, resp.StatusCode)
+	}
+	var doc struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return "", err
+	}
+	if doc.JWKSURI == "" {
+		return "", fmt.Errorf("discovery %s: jwks_uri missing", p.Name)
+	}
+	return doc.JWKSURI, nil
+}
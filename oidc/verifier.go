@@ -0,0 +1,85 @@
+// oidc/verifier.go
+package oidc
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Claims adalah identitas yang diambil dari ID token yang sudah terverifikasi
+type Claims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+	Locale        string // klaim "locale" standar OIDC, mis. "id" atau "en-US"
+}
+
+// Verifier memverifikasi ID token secara lokal: tanda tangan (JWKS), issuer,
+// audience (salah satu client ID), dan masa berlaku.
+type Verifier struct {
+	Issuers   []string
+	ClientIDs []string
+	Keys      *JWKSCache
+}
+
+var ErrInvalidToken = errors.New("invalid id token")
+
+func (v *Verifier) Verify(raw string) (*Claims, error) {
+	if len(v.ClientIDs) == 0 {
+		return nil, errors.New("no client ids configured")
+	}
+
+	token, err := jwt.Parse(raw, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.Keys.Key(kid)
+	}, jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384"}))
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, ErrInvalidToken
+	}
+
+	issuerOK := false
+	for _, iss := range v.Issuers {
+		if claims.VerifyIssuer(iss, true) {
+			issuerOK = true
+			break
+		}
+	}
+	audOK := false
+	for _, id := range v.ClientIDs {
+		if claims.VerifyAudience(id, true) {
+			audOK = true
+			break
+		}
+	}
+	if !issuerOK || !audOK {
+		return nil, ErrInvalidToken
+	}
+
+	c := &Claims{}
+	c.Issuer, _ = claims["iss"].(string)
+	c.Subject, _ = claims["sub"].(string)
+	c.Email, _ = claims["email"].(string)
+	c.Name, _ = claims["name"].(string)
+	c.Picture, _ = claims["picture"].(string)
+	c.Locale, _ = claims["locale"].(string)
+	// Beberapa penerbit mengirim email_verified sebagai string "true"
+	switch ev := claims["email_verified"].(type) {
+	case bool:
+		c.EmailVerified = ev
+	case string:
+		c.EmailVerified = ev == "true"
+	}
+	if c.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	return c, nil
+}
@@ -0,0 +1,108 @@
+// services/account_deletion.go
+package services
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"finance/models"
+	"finance/storage"
+	"finance/utils"
+
+	"gorm.io/gorm"
+)
+
+// DeleteUserData menghapus user beserta semua data miliknya. Jalankan di dalam transaksi.
+// Model baru yang punya kolom user_id wajib ditambahkan di sini.
+func DeleteUserData(tx *gorm.DB, user models.User) error {
+	owned := []interface{}{
+		&models.SubscriptionDismissal{},
+		&models.BillPayment{},
+		&models.Bill{},
+		&models.RecurringOccurrence{},
+		&models.RecurringTransaction{},
+		&models.TransactionSplit{},
+		&models.Transaction{},
+		&models.Transfer{},
+		&models.Account{},
+		&models.Budget{},
+		&models.Category{},
+		&models.Notification{},
+		&models.Backup{},
+		&models.BackupSchedule{},
+		&models.PasswordResetToken{},
+		&models.EmailVerificationToken{},
+		&models.RecoveryCode{},
+		&models.UserIdentity{},
+		&models.PersonalAccessToken{},
+	}
+	for _, m := range owned {
+		if err := tx.Where("user_id = ?", user.ID).Delete(m).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Where("session_id IN (?)", tx.Model(&models.Session{}).Select("id").Where("user_id = ?", user.ID)).
+		Delete(&models.RefreshToken{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.Session{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("attempt_key = ?", "account:"+strings.ToLower(user.Email)).Delete(&models.LoginAttempt{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&models.User{}, user.ID).Error
+}
+
+// removeUserFiles menghapus foto profil dari ./uploads bila tidak dipakai user lain
+func removeUserFiles(db *gorm.DB, user models.User) {
+	path := utils.UploadPath(user.PhotoURL)
+	if path == "" {
+		return
+	}
+	var shared int64
+	db.Model(&models.User{}).Where("photo_url = ?", user.PhotoURL).Count(&shared)
+	if shared > 0 {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Println("Gagal hapus file upload:", err)
+	}
+}
+
+// PurgeDeletedAccounts menghapus permanen akun yang masa tenggang penghapusannya sudah lewat
+func PurgeDeletedAccounts(db *gorm.DB) {
+	var users []models.User
+	if err := db.Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", time.Now()).Find(&users).Error; err != nil {
+		log.Println("Gagal ambil akun yang dijadwalkan dihapus:", err)
+		return
+	}
+	for _, u := range users {
+		var backups []models.Backup
+		db.Where("user_id = ?", u.ID).Find(&backups)
+		if err := db.Transaction(func(tx *gorm.DB) error { return DeleteUserData(tx, u) }); err != nil {
+			log.Printf("Gagal hapus akun %d: %v", u.ID, err)
+			continue
+		}
+		removeUserFiles(db, u)
+		for _, b := range backups {
+			if err := storage.Delete(b.BackupURL); err != nil {
+				log.Println("Gagal hapus arsip backup:", err)
+			}
+		}
+		log.Printf("Akun %d dihapus permanen", u.ID)
+	}
+}
+
+// StartAccountPurger menjalankan PurgeDeletedAccounts secara berkala di background
+func StartAccountPurger(db *gorm.DB, interval time.Duration) {
+	go func() {
+		for {
+			PurgeDeletedAccounts(db)
+			time.Sleep(interval)
+		}
+	}()
+}
@@ -0,0 +1,49 @@
+// services/account_service.go
+package services
+
+import (
+	"finance/models"
+	"finance/money"
+
+	"gorm.io/gorm"
+)
+
+// SignedAmountSQL adalah efek transaksi t (LEFT JOIN kategori c) terhadap saldo akun:
+// income dan leg transfer masuk menambah saldo, expense dan leg transfer keluar mengurangi
+const SignedAmountSQL = "CASE WHEN t.transfer_id IS NOT NULL THEN (CASE WHEN t.transfer_leg = 'in' THEN t.amount ELSE -t.amount END) " +
+	"WHEN c.type = 'income' THEN t.amount ELSE -t.amount END"
+
+// AccountBalances menghitung perubahan saldo (tanpa saldo awal) per akun milik user
+func AccountBalances(db *gorm.DB, userID uint) (map[uint]money.Amount, error) {
+	var rows []struct {
+		AccountID uint
+		Total     money.Amount
+	}
+	err := db.Raw(`
+        SELECT t.account_id, COALESCE(SUM(`+SignedAmountSQL+`),0) AS total
+        FROM transactions t
+        LEFT JOIN categories c ON t.category_id = c.id
+        WHERE t.user_id = ? AND t.account_id IS NOT NULL
+        GROUP BY t.account_id
+    `, userID).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	totals := make(map[uint]money.Amount, len(rows))
+	for _, r := range rows {
+		totals[r.AccountID] = r.Total
+	}
+	return totals, nil
+}
+
+// FillBalances mengisi field Balance = saldo awal + semua transaksi di akun
+func FillBalances(db *gorm.DB, userID uint, accounts []models.Account) error {
+	totals, err := AccountBalances(db, userID)
+	if err != nil {
+		return err
+	}
+	for i := range accounts {
+		accounts[i].Balance = accounts[i].OpeningBalance + totals[accounts[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,562 @@
+// services/backup.go
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"finance/models"
+	"finance/money"
+	"finance/storage"
+
+	"gorm.io/gorm"
+)
+
+// BackupVersion dinaikkan setiap kali format arsip berubah.
+// Arsip dengan versi lebih baru dari ini tidak bisa di-restore.
+//   - 1: kategori, transaksi, budget
+//   - 2: + akun dan account_id pada transaksi
+//   - 3: + transfer antar akun (leg transfer tanpa kategori)
+//   - 4: + split line per transaksi
+//   - 5: + mata uang per transaksi dan transfer
+//   - 6: + template transaksi berulang beserta kemunculannya
+//   - 7: + tagihan beserta riwayat pembayarannya
+//   - 8: + induk kategori (subkategori)
+//   - 9: + status arsip kategori
+const BackupVersion = 9
+
+var (
+	ErrBackupVersion = errors.New("unsupported backup version")
+	ErrBackupCorrupt = errors.New("backup archive is corrupt")
+)
+
+// BackupArchive adalah isi arsip backup (JSON yang di-gzip).
+// ID di dalam arsip adalah ID lama; saat restore semua ID dibuat ulang.
+type BackupArchive struct {
+	Version      int                 `json:"version"`
+	UserID       uint                `json:"user_id"`
+	CreatedAt    time.Time           `json:"created_at"`
+	Accounts     []BackupAccount     `json:"accounts"`
+	Categories   []BackupCategory    `json:"categories"`
+	Transactions []BackupTransaction `json:"transactions"`
+	Transfers    []BackupTransfer    `json:"transfers"`
+	Budgets      []BackupBudget      `json:"budgets"`
+	Recurring    []BackupRecurring   `json:"recurring"`
+	Bills        []BackupBill        `json:"bills"`
+}
+
+type BackupAccount struct {
+	ID             uint         `json:"id"`
+	Name           string       `json:"name"`
+	Type           string       `json:"type"`
+	OpeningBalance money.Amount `json:"opening_balance"`
+	Currency       string       `json:"currency"`
+}
+
+type BackupCategory struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Type       string     `json:"type"`
+	ParentID   *uint      `json:"parent_id,omitempty"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+}
+
+type BackupTransaction struct {
+	ID         uint          `json:"id"`
+	CategoryID uint          `json:"category_id"`
+	AccountID  *uint         `json:"account_id,omitempty"`
+	Amount     money.Amount  `json:"amount"`
+	Currency   string        `json:"currency,omitempty"`
+	Date       time.Time     `json:"date"`
+	Note       string        `json:"note"`
+	Splits     []BackupSplit `json:"splits,omitempty"`
+
+	RecurringID *uint `json:"recurring_id,omitempty"`
+}
+
+type BackupSplit struct {
+	CategoryID uint         `json:"category_id"`
+	Amount     money.Amount `json:"amount"`
+	Note       string       `json:"note"`
+}
+
+// Leg transfer tidak disimpan sebagai transaksi; dibentuk ulang dari transfer saat restore
+type BackupTransfer struct {
+	ID            uint         `json:"id"`
+	FromAccountID uint         `json:"from_account_id"`
+	ToAccountID   uint         `json:"to_account_id"`
+	Amount        money.Amount `json:"amount"`
+	Currency      string       `json:"currency,omitempty"`
+	Date          time.Time    `json:"date"`
+	Note          string       `json:"note"`
+}
+
+type BackupRecurring struct {
+	ID          uint               `json:"id"`
+	CategoryID  uint               `json:"category_id"`
+	AccountID   *uint              `json:"account_id,omitempty"`
+	Amount      money.Amount       `json:"amount"`
+	Currency    string             `json:"currency"`
+	Note        string             `json:"note"`
+	Frequency   string             `json:"frequency"`
+	Interval    int                `json:"interval"`
+	MonthDay    int                `json:"month_day"`
+	StartDate   time.Time          `json:"start_date"`
+	Until       *time.Time         `json:"until,omitempty"`
+	Count       int                `json:"count"`
+	NextIndex   int                `json:"next_index"`
+	Occurrences []BackupOccurrence `json:"occurrences,omitempty"`
+}
+
+type BackupOccurrence struct {
+	Date          time.Time     `json:"date"`
+	Status        string        `json:"status"`
+	TransactionID *uint         `json:"transaction_id,omitempty"`
+	CategoryID    *uint         `json:"category_id,omitempty"`
+	Amount        *money.Amount `json:"amount,omitempty"`
+	Note          *string       `json:"note,omitempty"`
+	Error         string        `json:"error,omitempty"`
+}
+
+type BackupBill struct {
+	ID            uint                `json:"id"`
+	Payee         string              `json:"payee"`
+	Amount        money.Amount        `json:"amount"`
+	Currency      string              `json:"currency"`
+	CategoryID    uint                `json:"category_id"`
+	AccountID     *uint               `json:"account_id,omitempty"`
+	Note          string              `json:"note"`
+	AutoPay       bool                `json:"auto_pay"`
+	RemindDays    int                 `json:"remind_days"`
+	Recurrence    models.Recurrence   `json:"recurrence"`
+	NextIndex     int                 `json:"next_index"`
+	RemindedIndex int                 `json:"reminded_index"`
+	OverdueIndex  int                 `json:"overdue_index"`
+	Payments      []BackupBillPayment `json:"payments,omitempty"`
+}
+
+type BackupBillPayment struct {
+	DueDate       time.Time `json:"due_date"`
+	TransactionID *uint     `json:"transaction_id,omitempty"`
+	AutoPaid      bool      `json:"auto_paid"`
+	PaidAt        time.Time `json:"paid_at"`
+}
+
+type BackupBudget struct {
+	ID          uint         `json:"id"`
+	CategoryID  uint         `json:"category_id"`
+	LimitAmount money.Amount `json:"limit_amount"`
+	StartDate   time.Time    `json:"start_date"`
+	EndDate     time.Time    `json:"end_date"`
+}
+
+// snapshot membaca data user saat ini ke dalam BackupArchive
+func snapshot(db *gorm.DB, userID uint) (*BackupArchive, error) {
+	var accounts []models.Account
+	var categories []models.Category
+	var transactions []models.Transaction
+	var splits []models.TransactionSplit
+	var transfers []models.Transfer
+	var budgets []models.Budget
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&splits).Error; err != nil {
+		return nil, err
+	}
+	splitsByTrx := map[uint][]BackupSplit{}
+	for _, s := range splits {
+		splitsByTrx[s.TransactionID] = append(splitsByTrx[s.TransactionID], BackupSplit{CategoryID: s.CategoryID, Amount: s.Amount, Note: s.Note})
+	}
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	var recurring []models.RecurringTransaction
+	var occurrences []models.RecurringOccurrence
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&recurring).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("user_id = ?", userID).Order("date").Find(&occurrences).Error; err != nil {
+		return nil, err
+	}
+	var bills []models.Bill
+	var payments []models.BillPayment
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&bills).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("user_id = ?", userID).Order("due_date").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	paymentsByBill := map[uint][]BackupBillPayment{}
+	for _, p := range payments {
+		paymentsByBill[p.BillID] = append(paymentsByBill[p.BillID], BackupBillPayment{
+			DueDate: p.DueDate, TransactionID: p.TransactionID, AutoPaid: p.AutoPaid, PaidAt: p.PaidAt,
+		})
+	}
+	occurrencesByRecurring := map[uint][]BackupOccurrence{}
+	for _, o := range occurrences {
+		occurrencesByRecurring[o.RecurringID] = append(occurrencesByRecurring[o.RecurringID], BackupOccurrence{
+			Date: o.Date, Status: o.Status, TransactionID: o.TransactionID,
+			CategoryID: o.CategoryID, Amount: o.Amount, Note: o.Note, Error: o.Error,
+		})
+	}
+	if err := db.Where("user_id = ? AND transfer_id IS NULL", userID).Order("id").Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+
+	archive := &BackupArchive{
+		Version:      BackupVersion,
+		UserID:       userID,
+		CreatedAt:    time.Now(),
+		Accounts:     make([]BackupAccount, 0, len(accounts)),
+		Categories:   make([]BackupCategory, 0, len(categories)),
+		Transactions: make([]BackupTransaction, 0, len(transactions)),
+		Transfers:    make([]BackupTransfer, 0, len(transfers)),
+		Budgets:      make([]BackupBudget, 0, len(budgets)),
+	}
+	for _, a := range accounts {
+		archive.Accounts = append(archive.Accounts, BackupAccount{
+			ID: a.ID, Name: a.Name, Type: a.Type, OpeningBalance: a.OpeningBalance, Currency: a.Currency,
+		})
+	}
+	for _, c := range categories {
+		archive.Categories = append(archive.Categories, BackupCategory{ID: c.ID, Name: c.Name, Type: c.Type, ParentID: c.ParentID, ArchivedAt: c.ArchivedAt})
+	}
+	for _, t := range transactions {
+		archive.Transactions = append(archive.Transactions, BackupTransaction{
+			ID: t.ID, CategoryID: t.CategoryID, AccountID: t.AccountID, Amount: t.Amount, Currency: t.Currency, Date: t.Date, Note: t.Note,
+			Splits: splitsByTrx[t.ID], RecurringID: t.RecurringID,
+		})
+	}
+	for _, t := range transfers {
+		archive.Transfers = append(archive.Transfers, BackupTransfer{
+			ID: t.ID, FromAccountID: t.FromAccountID, ToAccountID: t.ToAccountID, Amount: t.Amount, Currency: t.Currency, Date: t.Date, Note: t.Note,
+		})
+	}
+	for _, b := range budgets {
+		archive.Budgets = append(archive.Budgets, BackupBudget{
+			ID: b.ID, CategoryID: b.CategoryID, LimitAmount: b.LimitAmount, StartDate: b.StartDate, EndDate: b.EndDate,
+		})
+	}
+	for _, r := range recurring {
+		archive.Recurring = append(archive.Recurring, BackupRecurring{
+			ID: r.ID, CategoryID: r.CategoryID, AccountID: r.AccountID, Amount: r.Amount, Currency: r.Currency, Note: r.Note,
+			Frequency: r.Frequency, Interval: r.Interval, MonthDay: r.MonthDay, StartDate: r.StartDate, Until: r.Until,
+			Count: r.Count, NextIndex: r.NextIndex, Occurrences: occurrencesByRecurring[r.ID],
+		})
+	}
+	for _, b := range bills {
+		archive.Bills = append(archive.Bills, BackupBill{
+			ID: b.ID, Payee: b.Payee, Amount: b.Amount, Currency: b.Currency, CategoryID: b.CategoryID, AccountID: b.AccountID,
+			Note: b.Note, AutoPay: b.AutoPay, RemindDays: b.RemindDays, Recurrence: b.Recurrence,
+			NextIndex: b.NextIndex, RemindedIndex: b.RemindedIndex, OverdueIndex: b.OverdueIndex, Payments: paymentsByBill[b.ID],
+		})
+	}
+	return archive, nil
+}
+
+// CreateBackup menyimpan snapshot kategori, transaksi dan budget user ke storage
+// lalu mencatatnya di tabel backups. kind: models.BackupManual atau models.BackupScheduled
+func CreateBackup(db *gorm.DB, userID uint, kind string) (*models.Backup, error) {
+	var archive *BackupArchive
+	// Snapshot dibaca di dalam satu transaksi REPEATABLE READ (read-only) agar semua SELECT
+	// melihat data pada titik waktu yang sama; READ COMMITTED memberi snapshot per statement
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		archive, err = snapshot(tx, userID)
+		return err
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(archive); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("backups/%d/%s-v%d.json.gz", userID, archive.CreatedAt.UTC().Format("20060102T150405.000000000Z"), BackupVersion)
+	url, err := storage.Put(key, buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	backup := models.Backup{
+		UserID:    userID,
+		BackupURL: url,
+		Version:   BackupVersion,
+		SizeBytes: int64(buf.Len()),
+		Kind:      kind,
+	}
+	if err := db.Create(&backup).Error; err != nil {
+		storage.Delete(url)
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// LoadBackup membaca dan memvalidasi arsip backup dari storage
+func LoadBackup(backup models.Backup) (*BackupArchive, error) {
+	data, err := storage.Get(backup.BackupURL)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrBackupCorrupt
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, ErrBackupCorrupt
+	}
+	var archive BackupArchive
+	if err := json.Unmarshal(raw, &archive); err != nil {
+		return nil, ErrBackupCorrupt
+	}
+	if archive.Version < 1 || archive.Version > BackupVersion {
+		return nil, ErrBackupVersion
+	}
+	if archive.UserID != backup.UserID {
+		return nil, ErrBackupCorrupt
+	}
+	return &archive, nil
+}
+
+// RestoreBackup mengganti seluruh kategori, transaksi dan budget user dengan isi arsip.
+// Semua dijalankan dalam satu transaksi DB: gagal di tengah jalan berarti tidak ada yang berubah.
+// Baris dibuat dengan ID baru, referensi category_id/account_id dipetakan dari ID lama ke ID baru.
+// Arsip versi 1 belum berisi akun, sehingga akun yang ada sekarang dibiarkan.
+// Arsip sebelum versi 5 tanpa mata uang: transaksi ikut mata uang akunnya, atau mata uang dasar user.
+// Template recurring dan tagihan selalu diganti: arsip sebelum versi 6 (recurring) atau 7 (tagihan)
+// belum berisinya, dan data lama tidak bisa dipertahankan karena kategori, akun dan transaksi yang
+// dirujuknya dibuat ulang dengan ID baru.
+func RestoreBackup(db *gorm.DB, userID uint, archive *BackupArchive) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		replace := []interface{}{
+			&models.RecurringOccurrence{}, &models.RecurringTransaction{}, &models.BillPayment{}, &models.Bill{},
+			&models.Budget{}, &models.TransactionSplit{}, &models.Transaction{}, &models.Transfer{}, &models.Category{},
+		}
+		if archive.Version >= 2 {
+			replace = append(replace, &models.Account{})
+		}
+		for _, m := range replace {
+			if err := tx.Where("user_id = ?", userID).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+
+		accountIDs := make(map[uint]uint, len(archive.Accounts))
+		accountCurrency := make(map[uint]string, len(archive.Accounts))
+		for _, a := range archive.Accounts {
+			acc := models.Account{UserID: userID, Name: a.Name, Type: a.Type, OpeningBalance: a.OpeningBalance, Currency: a.Currency}
+			if err := tx.Create(&acc).Error; err != nil {
+				return err
+			}
+			accountIDs[a.ID] = acc.ID
+			accountCurrency[acc.ID] = acc.Currency
+		}
+		baseCurrency := BaseCurrency(tx, userID)
+
+		categoryIDs := make(map[uint]uint, len(archive.Categories))
+		for _, c := range archive.Categories {
+			cat := models.Category{UserID: userID, Name: c.Name, Type: c.Type, ArchivedAt: c.ArchivedAt}
+			if err := tx.Create(&cat).Error; err != nil {
+				return err
+			}
+			categoryIDs[c.ID] = cat.ID
+		}
+		// induk dipasang setelah semua kategori dibuat (urutan di arsip tidak dijamin)
+		for _, c := range archive.Categories {
+			if c.ParentID == nil {
+				continue
+			}
+			parentID, ok := categoryIDs[*c.ParentID]
+			if !ok {
+				return ErrBackupCorrupt
+			}
+			if err := tx.Model(&models.Category{}).Where("id = ?", categoryIDs[c.ID]).Update("parent_id", parentID).Error; err != nil {
+				return err
+			}
+		}
+
+		recurringIDs := make(map[uint]uint, len(archive.Recurring))
+		for _, r := range archive.Recurring {
+			catID, ok := categoryIDs[r.CategoryID]
+			if !ok {
+				return ErrBackupCorrupt
+			}
+			var accID *uint
+			if r.AccountID != nil {
+				id, ok := accountIDs[*r.AccountID]
+				if !ok {
+					return ErrBackupCorrupt
+				}
+				accID = &id
+			}
+			rec := models.RecurringTransaction{
+				UserID: userID, CategoryID: catID, AccountID: accID, Amount: r.Amount, Currency: r.Currency, Note: r.Note,
+				Recurrence: models.Recurrence{
+					Frequency: r.Frequency, Interval: r.Interval, MonthDay: r.MonthDay, StartDate: r.StartDate, Until: r.Until, Count: r.Count,
+				},
+				NextIndex: r.NextIndex,
+			}
+			rec.NextRunAt = NextRunFor(&rec.Recurrence, rec.NextIndex)
+			if err := tx.Create(&rec).Error; err != nil {
+				return err
+			}
+			recurringIDs[r.ID] = rec.ID
+		}
+
+		transactionIDs := make(map[uint]uint, len(archive.Transactions))
+		for _, t := range archive.Transactions {
+			catID, ok := categoryIDs[t.CategoryID]
+			if !ok {
+				return ErrBackupCorrupt
+			}
+			var accID *uint
+			if t.AccountID != nil {
+				id, ok := accountIDs[*t.AccountID]
+				if !ok {
+					return ErrBackupCorrupt
+				}
+				accID = &id
+			}
+			currency := t.Currency
+			if currency == "" && accID != nil {
+				currency = accountCurrency[*accID]
+			}
+			if currency == "" {
+				currency = baseCurrency
+			}
+			trx := models.Transaction{UserID: userID, CategoryID: catID, AccountID: accID, Amount: t.Amount, Currency: currency, Date: t.Date, Note: t.Note}
+			if t.RecurringID != nil {
+				if id, ok := recurringIDs[*t.RecurringID]; ok {
+					trx.RecurringID = &id
+				}
+			}
+			if err := tx.Create(&trx).Error; err != nil {
+				return err
+			}
+			transactionIDs[t.ID] = trx.ID
+			for _, s := range t.Splits {
+				splitCat, ok := categoryIDs[s.CategoryID]
+				if !ok {
+					return ErrBackupCorrupt
+				}
+				split := models.TransactionSplit{TransactionID: trx.ID, UserID: userID, CategoryID: splitCat, Amount: s.Amount, Note: s.Note}
+				if err := tx.Create(&split).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, r := range archive.Recurring {
+			for _, o := range r.Occurrences {
+				occ := models.RecurringOccurrence{
+					RecurringID: recurringIDs[r.ID], UserID: userID, Date: o.Date, Status: o.Status,
+					Amount: o.Amount, Note: o.Note, Error: o.Error,
+				}
+				if o.TransactionID != nil {
+					if id, ok := transactionIDs[*o.TransactionID]; ok {
+						occ.TransactionID = &id
+					}
+				}
+				if o.CategoryID != nil {
+					id, ok := categoryIDs[*o.CategoryID]
+					if !ok {
+						return ErrBackupCorrupt
+					}
+					occ.CategoryID = &id
+				}
+				if err := tx.Create(&occ).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, b := range archive.Bills {
+			catID, ok := categoryIDs[b.CategoryID]
+			if !ok {
+				return ErrBackupCorrupt
+			}
+			var accID *uint
+			if b.AccountID != nil {
+				id, ok := accountIDs[*b.AccountID]
+				if !ok {
+					return ErrBackupCorrupt
+				}
+				accID = &id
+			}
+			bill := models.Bill{
+				UserID: userID, Payee: b.Payee, Amount: b.Amount, Currency: b.Currency, CategoryID: catID, AccountID: accID,
+				Note: b.Note, AutoPay: b.AutoPay, RemindDays: b.RemindDays, Recurrence: b.Recurrence,
+				NextIndex: b.NextIndex, RemindedIndex: b.RemindedIndex, OverdueIndex: b.OverdueIndex,
+			}
+			bill.NextDueAt = NextRunFor(&bill.Recurrence, bill.NextIndex)
+			if err := tx.Create(&bill).Error; err != nil {
+				return err
+			}
+			for _, p := range b.Payments {
+				payment := models.BillPayment{BillID: bill.ID, UserID: userID, DueDate: p.DueDate, AutoPaid: p.AutoPaid, PaidAt: p.PaidAt}
+				if p.TransactionID != nil {
+					if id, ok := transactionIDs[*p.TransactionID]; ok {
+						payment.TransactionID = &id
+					}
+				}
+				if err := tx.Create(&payment).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, t := range archive.Transfers {
+			from, okFrom := accountIDs[t.FromAccountID]
+			to, okTo := accountIDs[t.ToAccountID]
+			if !okFrom || !okTo {
+				return ErrBackupCorrupt
+			}
+			transfer := models.Transfer{UserID: userID, FromAccountID: from, ToAccountID: to, Amount: t.Amount, Currency: accountCurrency[from], Date: t.Date, Note: t.Note}
+			if err := CreateTransfer(tx, &transfer); err != nil {
+				return err
+			}
+		}
+
+		for _, b := range archive.Budgets {
+			// Budget tanpa kategori (category_id 0) berlaku untuk semua pengeluaran
+			catID := uint(0)
+			if b.CategoryID != 0 {
+				var ok bool
+				if catID, ok = categoryIDs[b.CategoryID]; !ok {
+					return ErrBackupCorrupt
+				}
+			}
+			budget := models.Budget{
+				UserID:      userID,
+				CategoryID:  catID,
+				LimitAmount: b.LimitAmount,
+				StartDate:   b.StartDate,
+				EndDate:     b.EndDate,
+			}
+			if err := tx.Create(&budget).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
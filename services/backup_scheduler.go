@@ -0,0 +1,151 @@
+// services/backup_scheduler.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"finance/models"
+	"finance/storage"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidSchedule = errors.New("invalid backup schedule")
+
+// ValidateSchedule mengecek field jadwal backup dari API
+func ValidateSchedule(s *models.BackupSchedule) error {
+	if s.Frequency != "daily" && s.Frequency != "weekly" {
+		return fmt.Errorf("%w: frequency must be daily or weekly", ErrInvalidSchedule)
+	}
+	if s.Weekday < 0 || s.Weekday > 6 {
+		return fmt.Errorf("%w: weekday must be between 0 and 6", ErrInvalidSchedule)
+	}
+	if s.Hour < 0 || s.Hour > 23 {
+		return fmt.Errorf("%w: hour must be between 0 and 23", ErrInvalidSchedule)
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil || s.Timezone == "" {
+		return fmt.Errorf("%w: unknown timezone", ErrInvalidSchedule)
+	}
+	if s.KeepRecent < 1 || s.KeepRecent > 100 {
+		return fmt.Errorf("%w: keep_recent must be between 1 and 100", ErrInvalidSchedule)
+	}
+	if s.KeepMonthly < 0 || s.KeepMonthly > 120 {
+		return fmt.Errorf("%w: keep_monthly must be between 0 and 120", ErrInvalidSchedule)
+	}
+	return nil
+}
+
+// NextBackupRun menghitung jadwal berikutnya setelah waktu after, pada jam s.Hour di zona waktu jadwal
+func NextBackupRun(s models.BackupSchedule, after time.Time) time.Time {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	t := after.In(loc)
+	next := time.Date(t.Year(), t.Month(), t.Day(), s.Hour, 0, 0, 0, loc)
+	for !next.After(t) || (s.Frequency == "weekly" && int(next.Weekday()) != s.Weekday) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// PruneBackups menghapus backup terjadwal yang tidak lagi masuk kebijakan retensi:
+// KeepRecent backup terbaru, ditambah backup pertama tiap bulan untuk KeepMonthly bulan terakhir.
+// Backup manual tidak pernah dihapus otomatis.
+func PruneBackups(db *gorm.DB, s models.BackupSchedule) error {
+	var backups []models.Backup
+	if err := db.Where("user_id = ? AND kind = ?", s.UserID, models.BackupScheduled).
+		Order("created_at desc").Find(&backups).Error; err != nil {
+		return err
+	}
+
+	keep := map[uint]bool{}
+	for i := 0; i < len(backups) && i < s.KeepRecent; i++ {
+		keep[backups[i].ID] = true
+	}
+	// Urutan desc: backup terakhir yang ditemui untuk satu bulan adalah yang paling awal di bulan itu
+	monthly := map[string]uint{}
+	months := []string{}
+	for _, b := range backups {
+		m := b.CreatedAt.Format("2006-01")
+		if _, ok := monthly[m]; !ok {
+			months = append(months, m)
+		}
+		monthly[m] = b.ID
+	}
+	for i := 0; i < len(months) && i < s.KeepMonthly; i++ {
+		keep[monthly[months[i]]] = true
+	}
+
+	for _, b := range backups {
+		if keep[b.ID] {
+			continue
+		}
+		if err := storage.Delete(b.BackupURL); err != nil {
+			return err
+		}
+		if err := db.Delete(&b).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runScheduledBackup menjalankan satu jadwal; kegagalan dicatat sebagai notifikasi ke user
+func runScheduledBackup(db *gorm.DB, s models.BackupSchedule) {
+	now := time.Now()
+	updates := map[string]interface{}{"last_run_at": now, "last_error": ""}
+
+	_, err := CreateBackup(db, s.UserID, models.BackupScheduled)
+	if err == nil {
+		err = PruneBackups(db, s)
+	}
+	if err != nil {
+		log.Printf("Backup terjadwal user %d gagal: %v", s.UserID, err)
+		updates["last_error"] = err.Error()
+		notif := models.Notification{
+			UserID:    s.UserID,
+			Title:     "Backup Failed",
+			Message:   fmt.Sprintf("Backup otomatis %s gagal: %v", now.Format("02 Jan 2006 15:04"), err),
+			CreatedAt: now,
+		}
+		if err := db.Create(&notif).Error; err != nil {
+			log.Println("Gagal simpan notifikasi:", err)
+		}
+	}
+	db.Model(&models.BackupSchedule{}).Where("id = ?", s.ID).Updates(updates)
+}
+
+// RunDueBackups menjalankan semua jadwal yang sudah jatuh tempo. Jadwal yang terlewat
+// (mis. server mati) dijalankan sekali saja lalu dijadwalkan ulang dari waktu sekarang.
+func RunDueBackups(db *gorm.DB) {
+	now := time.Now()
+	var due []models.BackupSchedule
+	if err := db.Where("enabled = ? AND next_run_at <= ?", true, now).Find(&due).Error; err != nil {
+		log.Println("Gagal ambil jadwal backup:", err)
+		return
+	}
+	for _, s := range due {
+		// Klaim jadwal dengan menggeser next_run_at agar instance lain tidak menjalankannya juga
+		res := db.Model(&models.BackupSchedule{}).
+			Where("id = ? AND next_run_at = ?", s.ID, s.NextRunAt).
+			Update("next_run_at", NextBackupRun(s, now))
+		if res.Error != nil || res.RowsAffected == 0 {
+			continue
+		}
+		runScheduledBackup(db, s)
+	}
+}
+
+// StartBackupScheduler mengecek jadwal backup secara berkala di background
+func StartBackupScheduler(db *gorm.DB, interval time.Duration) {
+	go func() {
+		for {
+			RunDueBackups(db)
+			time.Sleep(interval)
+		}
+	}()
+}
@@ -0,0 +1,213 @@
+// services/bills.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"finance/models"
+	"finance/money"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// billCatchUpLimit membatasi jumlah jatuh tempo yang dibayar otomatis per tagihan per putaran
+const billCatchUpLimit = 100
+
+var (
+	ErrBillSettled = errors.New("bill has no unpaid due date")
+	errBillClaimed = errors.New("bill due date already processed")
+)
+
+// BillPayOptions mengubah transaksi pembayaran; nil = ikut tagihan
+type BillPayOptions struct {
+	Amount    *money.Amount
+	AccountID *uint
+	Date      *time.Time // default: sekarang
+	AutoPaid  bool
+	Index     *int // bila diisi, hanya bayar bila jatuh tempo tertua masih nomor ini (dipakai auto-pay)
+}
+
+// PayBill membayar jatuh tempo tertua yang belum dibayar dengan membuat transaksi lewat jalur
+// yang sama dengan POST /transactions, lalu menggeser tagihan ke jatuh tempo berikutnya.
+// Error validasi transaksi dikembalikan apa adanya (lihat IsTransactionInputError).
+func PayBill(db *gorm.DB, billID uint, opt BillPayOptions) (*models.BillPayment, *models.Transaction, error) {
+	var payment models.BillPayment
+	var trx *models.Transaction
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var bill models.Bill
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&bill, billID).Error; err != nil {
+			return err
+		}
+		if bill.NextDueAt == nil {
+			return ErrBillSettled
+		}
+		if opt.Index != nil && *opt.Index != bill.NextIndex {
+			return errBillClaimed
+		}
+		due := OccurrenceDate(&bill.Recurrence, bill.NextIndex)
+
+		in := NewTransaction{
+			CategoryID: bill.CategoryID,
+			AccountID:  bill.AccountID,
+			Amount:     bill.Amount,
+			Date:       time.Now(),
+			Note:       bill.Payee,
+			Currency:   bill.Currency,
+		}
+		if bill.Note != "" {
+			in.Note = bill.Payee + " - " + bill.Note
+		}
+		if opt.Amount != nil {
+			in.Amount = *opt.Amount
+		}
+		if opt.AccountID != nil {
+			in.AccountID = opt.AccountID
+			in.Currency = "" // ikut akun pembayar
+		}
+		if opt.Date != nil {
+			in.Date = *opt.Date
+		}
+		var cat *models.Category
+		var err error
+		trx, cat, err = BuildTransaction(tx, bill.UserID, in)
+		if err != nil {
+			return err
+		}
+		if err := CreateTransaction(tx, trx, nil); err != nil {
+			return err
+		}
+
+		payment = models.BillPayment{
+			BillID:        bill.ID,
+			UserID:        bill.UserID,
+			DueDate:       truncateDay(due),
+			TransactionID: &trx.ID,
+			AutoPaid:      opt.AutoPaid,
+			PaidAt:        time.Now(),
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		next := bill.NextIndex + 1
+		err = tx.Model(&bill).Updates(map[string]interface{}{
+			"next_index":  next,
+			"next_due_at": NextRunFor(&bill.Recurrence, next),
+		}).Error
+		if err != nil {
+			return err
+		}
+		CheckCategoryBudget(tx, bill.UserID, *cat)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &payment, trx, nil
+}
+
+// dueDay membandingkan per hari di zona waktu tanggal jatuh tempo
+func dueDay(t, due time.Time) time.Time {
+	return truncateDay(t.In(due.Location()))
+}
+
+func notifyBill(db *gorm.DB, bill models.Bill, title, message string) {
+	notif := models.Notification{UserID: bill.UserID, Title: title, Message: message, CreatedAt: time.Now()}
+	if err := db.Create(&notif).Error; err != nil {
+		log.Println("Gagal simpan notifikasi:", err)
+	}
+}
+
+// processBill mengirim pengingat, membayar otomatis, dan menandai overdue untuk satu tagihan
+func processBill(db *gorm.DB, bill models.Bill, now time.Time) {
+	for i := 0; i < billCatchUpLimit && bill.NextDueAt != nil; i++ {
+		due := *bill.NextDueAt
+		today := dueDay(now, due)
+		dueDate := truncateDay(due)
+		label := fmt.Sprintf("%s sebesar %s %s", bill.Payee, bill.Currency, bill.Amount)
+
+		// Pengingat N hari sebelum jatuh tempo; klaim dengan menggeser reminded_index
+		if bill.RemindedIndex <= bill.NextIndex && !today.Before(dueDate.AddDate(0, 0, -bill.RemindDays)) {
+			res := db.Model(&models.Bill{}).
+				Where("id = ? AND reminded_index = ?", bill.ID, bill.RemindedIndex).
+				Update("reminded_index", bill.NextIndex+1)
+			if res.Error == nil && res.RowsAffected == 1 {
+				msg := fmt.Sprintf("Tagihan %s jatuh tempo %s", label, due.Format("02 Jan 2006"))
+				if bill.AutoPay {
+					msg += " (dibayar otomatis)"
+				}
+				notifyBill(db, bill, "Bill Reminder", msg)
+			}
+		}
+
+		if today.Before(dueDate) {
+			return
+		}
+
+		if bill.AutoPay {
+			index := bill.NextIndex
+			_, _, err := PayBill(db, bill.ID, BillPayOptions{AutoPaid: true, Date: &due, Index: &index})
+			if err == nil {
+				notifyBill(db, bill, "Bill Paid", fmt.Sprintf("Tagihan %s jatuh tempo %s dibayar otomatis", label, due.Format("02 Jan 2006")))
+				if err := db.First(&bill, bill.ID).Error; err != nil {
+					return
+				}
+				continue
+			}
+			if errors.Is(err, errBillClaimed) {
+				return
+			}
+			// gagal (mis. akun dihapus): kabari sekali, menggantikan notifikasi overdue
+			log.Printf("Auto-pay tagihan %d gagal: %v", bill.ID, err)
+			if claimOverdue(db, bill) {
+				notifyBill(db, bill, "Bill Auto-Pay Failed", fmt.Sprintf("Tagihan %s gagal dibayar otomatis: %v", label, err))
+			}
+			return
+		}
+
+		// Overdue: sehari setelah jatuh tempo dan belum dibayar
+		if today.After(dueDate) && claimOverdue(db, bill) {
+			notifyBill(db, bill, "Bill Overdue", fmt.Sprintf("Tagihan %s sudah lewat jatuh tempo %s", label, due.Format("02 Jan 2006")))
+		}
+		return
+	}
+}
+
+// claimOverdue menandai notifikasi overdue jatuh tempo saat ini sudah dikirim; false bila sudah pernah
+func claimOverdue(db *gorm.DB, bill models.Bill) bool {
+	if bill.OverdueIndex > bill.NextIndex {
+		return false
+	}
+	res := db.Model(&models.Bill{}).
+		Where("id = ? AND overdue_index = ?", bill.ID, bill.OverdueIndex).
+		Update("overdue_index", bill.NextIndex+1)
+	return res.Error == nil && res.RowsAffected == 1
+}
+
+// RunBillJobs memproses tagihan yang masuk masa pengingat, jatuh tempo, atau lewat jatuh tempo
+func RunBillJobs(db *gorm.DB) {
+	now := time.Now()
+	var bills []models.Bill
+	err := db.Where("next_due_at IS NOT NULL AND next_due_at - make_interval(days => remind_days + 1) <= ?", now).
+		Find(&bills).Error
+	if err != nil {
+		log.Println("Gagal ambil tagihan:", err)
+		return
+	}
+	for _, b := range bills {
+		processBill(db, b, now)
+	}
+}
+
+// StartBillReminder menjalankan RunBillJobs secara berkala di background
+func StartBillReminder(db *gorm.DB, interval time.Duration) {
+	go func() {
+		for {
+			RunBillJobs(db)
+			time.Sleep(interval)
+		}
+	}()
+}
@@ -3,16 +3,20 @@ package services
 
 import (
 	"finance/models"
+	"finance/money"
 
 	"gorm.io/gorm"
 )
 
+// CalculateSpentAmount menghitung pemakaian budget, termasuk subkategori dari kategori budget
 func CalculateSpentAmount(db *gorm.DB, budget *models.Budget) {
-	var total float64
-	db.Model(&models.Transaction{}).
-		Where("user_id = ? AND category_id = ? AND date BETWEEN ? AND ?",
-			budget.UserID, budget.CategoryID, budget.StartDate, budget.EndDate).
-		Select("SUM(amount)").Scan(&total)
+	var total money.Amount
+	base := BaseCurrency(db, budget.UserID)
+	db.Raw(`
+        SELECT COALESCE(SUM(`+ConvertSQL("tl.amount", "tl.currency", "tl.date", base)+`),0)
+        FROM `+CategoryLinesSQL+` tl
+        WHERE tl.user_id = ? AND `+InCategoryTreeSQL("tl.category_id", "?")+` AND tl.date BETWEEN ? AND ? AND tl.transfer_id IS NULL
+    `, budget.UserID, budget.CategoryID, budget.StartDate, budget.EndDate).Scan(&total)
 
 	budget.SpentAmount = total
 	if total > budget.LimitAmount {
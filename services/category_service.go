@@ -0,0 +1,189 @@
+// services/category_service.go
+package services
+
+import (
+	"errors"
+	"time"
+
+	"finance/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrMergeSameCategory = errors.New("cannot merge a category into itself")
+	ErrMergeType         = errors.New("categories must have the same type")
+	ErrMergeDescendant   = errors.New("cannot merge a category into one of its subcategories")
+	ErrMergeTarget       = errors.New("invalid target category")
+	ErrMergeArchived     = errors.New("target category is archived; unarchive it first")
+	ErrMergeBudget       = errors.New("target category already has a budget overlapping a different period")
+)
+
+// categoryReferences adalah tabel yang menyimpan category_id milik user
+var categoryReferences = []struct {
+	Name  string
+	Model interface{}
+}{
+	{"transactions", &models.Transaction{}},
+	{"splits", &models.TransactionSplit{}},
+	{"budgets", &models.Budget{}},
+	{"recurring", &models.RecurringTransaction{}},
+	{"occurrences", &models.RecurringOccurrence{}},
+	{"bills", &models.Bill{}},
+}
+
+// MergeCategories memindahkan semua transaksi, split line, budget, template recurring
+// (termasuk override kemunculan) dan tagihan dari source ke target, memindahkan subkategori
+// source ke bawah target, lalu menghapus source. Panggil di dalam db.Transaction.
+// Budget dengan periode yang sama digabung (limit dijumlah); subkategori yang namanya sudah
+// ada di bawah target digabung ke subkategori itu. Mengembalikan jumlah baris per jenis data.
+func MergeCategories(tx *gorm.DB, userID, sourceID, targetID uint) (map[string]int64, error) {
+	if sourceID == targetID {
+		return nil, ErrMergeSameCategory
+	}
+	var cats []models.Category
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ? AND user_id = ?", []uint{sourceID, targetID}, userID).Order("id").Find(&cats).Error
+	if err != nil {
+		return nil, err
+	}
+	var source, target *models.Category
+	for i := range cats {
+		if cats[i].ID == sourceID {
+			source = &cats[i]
+		} else {
+			target = &cats[i]
+		}
+	}
+	if source == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if target == nil {
+		return nil, ErrMergeTarget
+	}
+	if source.Type != target.Type {
+		return nil, ErrMergeType
+	}
+	if target.ArchivedAt != nil {
+		return nil, ErrMergeArchived
+	}
+	for _, a := range CategoryAncestors(tx, *target) {
+		if a.ID == source.ID {
+			return nil, ErrMergeDescendant
+		}
+	}
+
+	// budget source yang periodenya sama dengan budget target digabung; periode yang hanya
+	// beririsan ditolak karena CheckCategoryBudget hanya membaca satu budget per kategori
+	var budgets []models.Budget
+	if err := tx.Where("category_id = ? AND user_id = ?", source.ID, userID).Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+	var mergedBudgets int64
+	for _, b := range budgets {
+		var overlap []models.Budget
+		err := tx.Where("category_id = ? AND user_id = ? AND start_date <= ? AND end_date >= ?", target.ID, userID, b.EndDate, b.StartDate).
+			Find(&overlap).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(overlap) == 0 {
+			continue
+		}
+		if len(overlap) > 1 || !overlap[0].StartDate.Equal(b.StartDate) || !overlap[0].EndDate.Equal(b.EndDate) {
+			return nil, ErrMergeBudget
+		}
+		if err := tx.Model(&overlap[0]).Update("limit_amount", overlap[0].LimitAmount+b.LimitAmount).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Delete(&b).Error; err != nil {
+			return nil, err
+		}
+		mergedBudgets++
+	}
+
+	moved := map[string]int64{}
+	for _, ref := range categoryReferences {
+		res := tx.Model(ref.Model).Where("category_id = ? AND user_id = ?", source.ID, userID).Update("category_id", target.ID)
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		moved[ref.Name] = res.RowsAffected
+	}
+	moved["budgets"] += mergedBudgets
+
+	// subkategori pindah ke target; kedalaman pohon tetap dicek. Subkategori yang namanya
+	// sudah dipakai anak target digabung ke anak itu agar tidak ada dua nama yang sama.
+	var children []models.Category
+	if err := tx.Where("parent_id = ? AND user_id = ?", source.ID, userID).Find(&children).Error; err != nil {
+		return nil, err
+	}
+	for i := range children {
+		var same models.Category
+		err := tx.Where("parent_id = ? AND user_id = ? AND type = ? AND LOWER(name) = LOWER(?)", target.ID, userID, children[i].Type, children[i].Name).
+			First(&same).Error
+		if err == nil {
+			sub, err := MergeCategories(tx, userID, children[i].ID, same.ID)
+			if err != nil {
+				return nil, err
+			}
+			for name, n := range sub {
+				moved[name] += n
+			}
+			moved["merged_subcategories"]++
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err := ValidateCategoryParent(tx, userID, &children[i], target.ID); err != nil && !errors.Is(err, ErrParentArchived) {
+			return nil, err
+		}
+		if err := tx.Model(&children[i]).Update("parent_id", target.ID).Error; err != nil {
+			return nil, err
+		}
+		moved["subcategories"]++
+	}
+
+	if err := tx.Delete(source).Error; err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+// ArchiveCategory mengarsipkan kategori beserta semua subkategorinya yang belum diarsipkan.
+// Semua diberi waktu arsip yang sama agar bisa dipulihkan bersama (lihat UnarchiveCategory).
+func ArchiveCategory(db *gorm.DB, cat *models.Category) error {
+	now := time.Now().Truncate(time.Microsecond) // presisi timestamp Postgres
+	err := db.Model(&models.Category{}).
+		Where("user_id = ? AND archived_at IS NULL AND "+InCategoryTreeSQL("id", "?"), cat.UserID, cat.ID).
+		Update("archived_at", now).Error
+	if err != nil {
+		return err
+	}
+	cat.ArchivedAt = &now
+	return nil
+}
+
+// UnarchiveCategory memulihkan kategori beserta subkategori yang ikut diarsipkan bersamanya;
+// subkategori yang diarsipkan sendiri sebelumnya tetap diarsipkan
+func UnarchiveCategory(db *gorm.DB, cat *models.Category) error {
+	if cat.ArchivedAt == nil {
+		return nil
+	}
+	if cat.ParentID != nil {
+		var parent models.Category
+		if err := db.First(&parent, *cat.ParentID).Error; err == nil && parent.ArchivedAt != nil {
+			return ErrParentArchived
+		}
+	}
+	err := db.Model(&models.Category{}).
+		Where("user_id = ? AND archived_at = ? AND "+InCategoryTreeSQL("id", "?"), cat.UserID, *cat.ArchivedAt, cat.ID).
+		Update("archived_at", nil).Error
+	if err != nil {
+		return err
+	}
+	cat.ArchivedAt = nil
+	return nil
+}
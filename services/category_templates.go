@@ -0,0 +1,204 @@
+// services/category_templates.go
+package services
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+
+	"finance/models"
+
+	"gorm.io/gorm"
+)
+
+// DefaultTemplateLocale dipakai bila bahasa yang diminta tidak punya template
+const DefaultTemplateLocale = "id"
+
+var (
+	ErrUnknownTemplateLocale = errors.New("unknown template locale")
+	ErrInvalidTemplateParent = errors.New("parent must be a top-level template with the same locale and type")
+
+	templateLocale = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+)
+
+type builtinTemplate struct {
+	Name     string
+	Type     string
+	Children []string
+}
+
+// builtinTemplates mengisi tabel category_templates saat pertama kali kosong;
+// setelah itu admin mengubah template lewat /admin/category-templates
+var builtinTemplates = map[string][]builtinTemplate{
+	"id": {
+		{"Makanan & Minuman", "expense", []string{"Restoran", "Belanja Dapur"}},
+		{"Transportasi", "expense", []string{"Bensin", "Transportasi Umum"}},
+		{"Tagihan & Utilitas", "expense", []string{"Listrik", "Air", "Internet & Pulsa"}},
+		{"Belanja", "expense", nil},
+		{"Kesehatan", "expense", nil},
+		{"Pendidikan", "expense", nil},
+		{"Hiburan", "expense", nil},
+		{"Langganan", "expense", nil},
+		{"Rumah Tangga", "expense", nil},
+		{"Lainnya", "expense", nil},
+		{"Gaji", "income", nil},
+		{"Bonus", "income", nil},
+		{"Usaha", "income", nil},
+		{"Investasi", "income", nil},
+		{"Hadiah", "income", nil},
+		{"Lainnya", "income", nil},
+	},
+	"en": {
+		{"Food & Drinks", "expense", []string{"Restaurants", "Groceries"}},
+		{"Transportation", "expense", []string{"Fuel", "Public Transport"}},
+		{"Bills & Utilities", "expense", []string{"Electricity", "Water", "Internet & Phone"}},
+		{"Shopping", "expense", nil},
+		{"Health", "expense", nil},
+		{"Education", "expense", nil},
+		{"Entertainment", "expense", nil},
+		{"Subscriptions", "expense", nil},
+		{"Household", "expense", nil},
+		{"Other", "expense", nil},
+		{"Salary", "income", nil},
+		{"Bonus", "income", nil},
+		{"Business", "income", nil},
+		{"Investments", "income", nil},
+		{"Gifts", "income", nil},
+		{"Other", "income", nil},
+	},
+}
+
+// SeedCategoryTemplates mengisi template bawaan bila tabel template masih kosong
+func SeedCategoryTemplates(db *gorm.DB) {
+	var count int64
+	if err := db.Model(&models.CategoryTemplate{}).Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for locale, list := range builtinTemplates {
+			for _, t := range list {
+				parent := models.CategoryTemplate{Locale: locale, Name: t.Name, Type: t.Type}
+				if err := tx.Create(&parent).Error; err != nil {
+					return err
+				}
+				for _, name := range t.Children {
+					child := models.CategoryTemplate{Locale: locale, Name: name, Type: t.Type, ParentID: &parent.ID}
+					if err := tx.Create(&child).Error; err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println("Gagal isi template kategori:", err)
+	}
+}
+
+// ValidTemplateLocale: kode bahasa seperti "id", "en" atau "en-US"
+func ValidTemplateLocale(locale string) bool {
+	return templateLocale.MatchString(locale)
+}
+
+// TemplateLocales mengembalikan daftar locale yang punya template
+func TemplateLocales(db *gorm.DB) []string {
+	var locales []string
+	db.Model(&models.CategoryTemplate{}).Distinct("locale").Order("locale").Pluck("locale", &locales)
+	return locales
+}
+
+// PickTemplateLocale memilih set template dari preferensi user: "en-US" -> "en-US" atau "en".
+// Preferensi boleh berupa header Accept-Language ("en-US,en;q=0.9"); default DefaultTemplateLocale.
+func PickTemplateLocale(db *gorm.DB, preference string) string {
+	available := map[string]bool{}
+	for _, l := range TemplateLocales(db) {
+		available[l] = true
+	}
+	for _, part := range strings.Split(preference, ",") {
+		tag := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if tag == "" {
+			continue
+		}
+		lang, region, _ := strings.Cut(tag, "-")
+		lang = strings.ToLower(lang)
+		if region != "" && available[lang+"-"+strings.ToUpper(region)] {
+			return lang + "-" + strings.ToUpper(region)
+		}
+		if available[lang] {
+			return lang
+		}
+	}
+	return DefaultTemplateLocale
+}
+
+// ValidateTemplateParent memastikan induk adalah template utama dengan locale dan tipe yang sama
+func ValidateTemplateParent(db *gorm.DB, t *models.CategoryTemplate, parentID uint) error {
+	var parent models.CategoryTemplate
+	if err := db.First(&parent, parentID).Error; err != nil {
+		return ErrInvalidTemplateParent
+	}
+	if parent.ID == t.ID || parent.ParentID != nil || parent.Locale != t.Locale || parent.Type != t.Type {
+		return ErrInvalidTemplateParent
+	}
+	return nil
+}
+
+// ApplyCategoryTemplate menyalin template locale ke kategori user. Kategori yang sudah ada
+// (nama, tipe dan induk sama) dilewati, sehingga aman dijalankan ulang. Mengembalikan jumlah
+// kategori baru.
+func ApplyCategoryTemplate(db *gorm.DB, userID uint, locale string) (int, error) {
+	var templates []models.CategoryTemplate
+	if err := db.Where("locale = ?", locale).Order("id").Find(&templates).Error; err != nil {
+		return 0, err
+	}
+	if len(templates) == 0 {
+		return 0, ErrUnknownTemplateLocale
+	}
+
+	created := 0
+	categoryIDs := map[uint]uint{} // template ID -> kategori user
+	apply := func(t models.CategoryTemplate, parentID *uint) error {
+		q := db.Where("user_id = ? AND LOWER(name) = LOWER(?) AND type = ?", userID, t.Name, t.Type)
+		if parentID == nil {
+			q = q.Where("parent_id IS NULL")
+		} else {
+			q = q.Where("parent_id = ?", *parentID)
+		}
+		var cat models.Category
+		if err := q.First(&cat).Error; err == nil {
+			categoryIDs[t.ID] = cat.ID
+			return nil
+		}
+		cat = models.Category{UserID: userID, Name: t.Name, Type: t.Type, ParentID: parentID}
+		if err := db.Create(&cat).Error; err != nil {
+			return err
+		}
+		categoryIDs[t.ID] = cat.ID
+		created++
+		return nil
+	}
+
+	// template utama dulu, lalu subkategorinya
+	for _, t := range templates {
+		if t.ParentID == nil {
+			if err := apply(t, nil); err != nil {
+				return created, err
+			}
+		}
+	}
+	for _, t := range templates {
+		if t.ParentID == nil {
+			continue
+		}
+		parentID, ok := categoryIDs[*t.ParentID]
+		if !ok {
+			continue
+		}
+		if err := apply(t, &parentID); err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
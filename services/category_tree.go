@@ -0,0 +1,184 @@
+// services/category_tree.go
+package services
+
+import (
+	"errors"
+	"strconv"
+
+	"finance/models"
+	"finance/money"
+
+	"gorm.io/gorm"
+)
+
+// categoryMaxDepth membatasi kedalaman pohon kategori (juga pengaman saat menelusuri induk)
+const categoryMaxDepth = 5
+
+var (
+	ErrInvalidParent  = errors.New("invalid parent category")
+	ErrParentCycle    = errors.New("parent category cannot be the category itself or one of its subcategories")
+	ErrParentType     = errors.New("parent category must have the same type")
+	ErrCategoryDepth  = errors.New("category tree is too deep")
+	ErrParentArchived = errors.New("parent category is archived")
+)
+
+// CategoryTreeSQL adalah subquery (category_id): kategori root beserta semua turunannya.
+// Rekursi dimulai dari root saja (bukan seluruh tabel categories), sehingga yang ditelusuri
+// hanya subtree milik root. root berupa placeholder "?" atau kolom, mis. "b.category_id".
+func CategoryTreeSQL(root string) string {
+	return `(
+        WITH RECURSIVE tree AS (
+            SELECT id AS category_id, 0 AS depth FROM categories WHERE id = ` + root + `
+            UNION ALL
+            SELECT c.id, tree.depth + 1
+            FROM categories c JOIN tree ON c.parent_id = tree.category_id
+            WHERE tree.depth < ` + strconv.Itoa(categoryMaxDepth) + `
+        )
+        SELECT category_id FROM tree
+    )`
+}
+
+// InCategoryTreeSQL menghasilkan kondisi "column ada di kategori root atau turunannya"
+func InCategoryTreeSQL(column, root string) string {
+	return column + " IN " + CategoryTreeSQL(root)
+}
+
+// ValidateCategoryParent memastikan induk milik user, bertipe sama, tidak membentuk siklus,
+// dan pohon tidak melebihi categoryMaxDepth. cat.ID 0 berarti kategori baru.
+func ValidateCategoryParent(db *gorm.DB, userID uint, cat *models.Category, parentID uint) error {
+	var parent models.Category
+	if err := db.Where("id = ? AND user_id = ?", parentID, userID).First(&parent).Error; err != nil {
+		return ErrInvalidParent
+	}
+	if parent.Type != cat.Type {
+		return ErrParentType
+	}
+	if parent.ArchivedAt != nil {
+		return ErrParentArchived
+	}
+
+	// telusuri ke atas dari induk; siklus bila ketemu kategori ini sendiri
+	depth := 1
+	for p := &parent; ; depth++ {
+		if cat.ID != 0 && p.ID == cat.ID {
+			return ErrParentCycle
+		}
+		if p.ParentID == nil {
+			break
+		}
+		if depth >= categoryMaxDepth {
+			return ErrCategoryDepth
+		}
+		var next models.Category
+		if err := db.First(&next, *p.ParentID).Error; err != nil {
+			break
+		}
+		p = &next
+	}
+	if cat.ID != 0 {
+		depth += categorySubtreeHeight(db, cat.ID)
+	}
+	if depth >= categoryMaxDepth {
+		return ErrCategoryDepth
+	}
+	return nil
+}
+
+// categorySubtreeHeight: jumlah tingkat subkategori di bawah kategori id (0 = tanpa anak)
+func categorySubtreeHeight(db *gorm.DB, id uint) int {
+	height := 0
+	level := []uint{id}
+	for height < categoryMaxDepth {
+		var children []uint
+		db.Model(&models.Category{}).Where("parent_id IN ?", level).Pluck("id", &children)
+		if len(children) == 0 {
+			break
+		}
+		height++
+		level = children
+	}
+	return height
+}
+
+// CategoryAncestors mengembalikan kategori beserta induk-induknya, mulai dari kategori itu sendiri
+func CategoryAncestors(db *gorm.DB, cat models.Category) []models.Category {
+	chain := []models.Category{cat}
+	for len(chain) < categoryMaxDepth && cat.ParentID != nil {
+		var parent models.Category
+		if err := db.First(&parent, *cat.ParentID).Error; err != nil {
+			break
+		}
+		chain = append(chain, parent)
+		cat = parent
+	}
+	return chain
+}
+
+// CategoryNode adalah kategori beserta subkategorinya (untuk GET /categories)
+type CategoryNode struct {
+	models.Category
+	Children []*CategoryNode
+}
+
+// BuildCategoryTree menyusun daftar kategori menjadi pohon; kategori yang induknya tidak
+// ada di daftar ditampilkan sebagai kategori utama
+func BuildCategoryTree(cats []models.Category) []*CategoryNode {
+	nodes := make(map[uint]*CategoryNode, len(cats))
+	for _, c := range cats {
+		nodes[c.ID] = &CategoryNode{Category: c, Children: []*CategoryNode{}}
+	}
+	roots := []*CategoryNode{}
+	for _, c := range cats {
+		n := nodes[c.ID]
+		if c.ParentID != nil {
+			if parent, ok := nodes[*c.ParentID]; ok {
+				parent.Children = append(parent.Children, n)
+				continue
+			}
+		}
+		roots = append(roots, n)
+	}
+	return roots
+}
+
+// CategoryRoots memetakan setiap kategori ke kategori utamanya (induk paling atas)
+func CategoryRoots(cats []models.Category) map[uint]uint {
+	byID := make(map[uint]models.Category, len(cats))
+	for _, c := range cats {
+		byID[c.ID] = c
+	}
+	roots := make(map[uint]uint, len(cats))
+	for _, c := range cats {
+		root := c
+		for i := 0; i < categoryMaxDepth && root.ParentID != nil; i++ {
+			parent, ok := byID[*root.ParentID]
+			if !ok {
+				break
+			}
+			root = parent
+		}
+		roots[c.ID] = root.ID
+	}
+	return roots
+}
+
+// RollUpCategoryTotals menjumlahkan total langsung tiap kategori ke semua induknya,
+// sehingga total kategori induk mencakup seluruh subkategorinya
+func RollUpCategoryTotals(cats []models.Category, direct map[uint]money.Amount) map[uint]money.Amount {
+	byID := make(map[uint]models.Category, len(cats))
+	for _, c := range cats {
+		byID[c.ID] = c
+	}
+	rolled := make(map[uint]money.Amount, len(cats))
+	for id, amount := range direct {
+		c, ok := byID[id]
+		for i := 0; ok && i < categoryMaxDepth; i++ {
+			rolled[c.ID] += amount
+			if c.ParentID == nil {
+				break
+			}
+			c, ok = byID[*c.ParentID]
+		}
+	}
+	return rolled
+}
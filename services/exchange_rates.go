@@ -0,0 +1,238 @@
+// services/exchange_rates.go
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"finance/models"
+	"finance/money"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrRateNotFound = errors.New("exchange rate not found")
+	currencyCode    = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// ValidCurrency mengecek kode mata uang ISO 4217 (3 huruf kapital)
+func ValidCurrency(code string) bool {
+	return currencyCode.MatchString(code)
+}
+
+// RateProvider adalah sumber kurs eksternal. Rate mengembalikan nilai 1 base dalam quote
+// pada tanggal date (atau tanggal terakhir sebelumnya yang tersedia).
+type RateProvider interface {
+	Name() string
+	Rate(base, quote string, date time.Time) (money.Rate, error)
+}
+
+// Rates adalah provider aktif; nil berarti kurs hanya dari tabel exchange_rates (input admin)
+var Rates RateProvider
+
+// InitRateProvider memilih provider dari environment:
+//   - RATE_PROVIDER=file: kurs dibaca dari CSV di RATES_FILE (kolom: date,base,quote,rate)
+//   - selain itu: tanpa provider
+func InitRateProvider() {
+	switch os.Getenv("RATE_PROVIDER") {
+	case "file":
+		p, err := LoadFileRateProvider(os.Getenv("RATES_FILE"))
+		if err != nil {
+			log.Fatal("Failed to load exchange rates file:", err)
+		}
+		Rates = p
+		log.Printf("Exchange rates loaded from %s (%d rates)", p.Path, p.Len())
+	}
+}
+
+// ParsedRate adalah satu baris kurs dari CSV
+type ParsedRate struct {
+	Date  time.Time
+	Base  string
+	Quote string
+	Rate  money.Rate
+}
+
+// ParseRatesCSV membaca CSV "date,base,quote,rate" (header opsional, tanggal YYYY-MM-DD)
+func ParseRatesCSV(r io.Reader) ([]ParsedRate, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 4
+	cr.TrimLeadingSpace = true
+	var rates []ParsedRate
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && strings.EqualFold(rec[0], "date") {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", rec[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q", line, rec[0])
+		}
+		base, quote := strings.ToUpper(rec[1]), strings.ToUpper(rec[2])
+		if !ValidCurrency(base) || !ValidCurrency(quote) || base == quote {
+			return nil, fmt.Errorf("line %d: invalid currency pair %s/%s", line, rec[1], rec[2])
+		}
+		rate, err := money.ParseRate(rec[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rate %q", line, rec[3])
+		}
+		rates = append(rates, ParsedRate{Date: date, Base: base, Quote: quote, Rate: rate})
+	}
+	return rates, nil
+}
+
+// FileRateProvider menyimpan kurs dari file CSV di memori, untuk pemakaian offline
+type FileRateProvider struct {
+	Path  string
+	mu    sync.RWMutex
+	rates map[string][]ParsedRate // "BASE/QUOTE" -> urut tanggal naik
+}
+
+func LoadFileRateProvider(path string) (*FileRateProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	parsed, err := ParseRatesCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	p := &FileRateProvider{Path: path, rates: map[string][]ParsedRate{}}
+	for _, r := range parsed {
+		key := r.Base + "/" + r.Quote
+		p.rates[key] = append(p.rates[key], r)
+	}
+	for _, list := range p.rates {
+		sort.Slice(list, func(i, j int) bool { return list[i].Date.Before(list[j].Date) })
+	}
+	return p, nil
+}
+
+func (p *FileRateProvider) Name() string { return "file" }
+
+func (p *FileRateProvider) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	n := 0
+	for _, list := range p.rates {
+		n += len(list)
+	}
+	return n
+}
+
+func (p *FileRateProvider) Rate(base, quote string, date time.Time) (money.Rate, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if r, ok := latestOnOrBefore(p.rates[base+"/"+quote], date); ok {
+		return r, nil
+	}
+	if r, ok := latestOnOrBefore(p.rates[quote+"/"+base], date); ok {
+		return r.Inverse()
+	}
+	return "", ErrRateNotFound
+}
+
+func latestOnOrBefore(list []ParsedRate, date time.Time) (money.Rate, bool) {
+	day := truncateDay(date)
+	for i := len(list) - 1; i >= 0; i-- {
+		if !list[i].Date.After(day) {
+			return list[i].Rate, true
+		}
+	}
+	return "", false
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// SaveRate menyimpan (upsert) satu kurs ke tabel exchange_rates
+func SaveRate(db *gorm.DB, r ParsedRate, source string) error {
+	rate := models.ExchangeRate{Base: r.Base, Quote: r.Quote, Date: truncateDay(r.Date), Rate: r.Rate, Source: source}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "base"}, {Name: "quote"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source"}),
+	}).Create(&rate).Error
+}
+
+// ConvertSQL mengonversi ekspresi amount bermata uang currency (kolom SQL) ke mata uang base
+// memakai kurs terakhir pada atau sebelum tanggal date. Pasangan terbalik dipakai dengan 1/rate.
+// Hasilnya NULL bila kurs tidak ditemukan (tidak ikut dijumlahkan SUM).
+// base harus sudah lolos ValidCurrency karena ditulis langsung ke SQL.
+func ConvertSQL(amount, currency, date, base string) string {
+	if !ValidCurrency(base) {
+		base = "IDR"
+	}
+	return fmt.Sprintf(`(CASE WHEN %[2]s = '%[4]s' THEN %[1]s ELSE ROUND(%[1]s * (
+                SELECT CASE WHEN er.base = %[2]s THEN er.rate ELSE 1 / er.rate END
+                FROM exchange_rates er
+                WHERE ((er.base = %[2]s AND er.quote = '%[4]s') OR (er.base = '%[4]s' AND er.quote = %[2]s))
+                  AND er.date <= CAST(%[3]s AS date)
+                ORDER BY er.date DESC LIMIT 1
+            ), 2) END)`, amount, currency, date, base)
+}
+
+// BaseCurrency mengembalikan mata uang dasar user (default IDR)
+func BaseCurrency(db *gorm.DB, userID uint) string {
+	var user models.User
+	if err := db.Select("base_currency").First(&user, userID).Error; err != nil || !ValidCurrency(user.BaseCurrency) {
+		return "IDR"
+	}
+	return user.BaseCurrency
+}
+
+// EnsureRates melengkapi tabel exchange_rates dari provider untuk setiap (mata uang, tanggal)
+// transaksi user yang belum punya kurs ke base. Mengembalikan jumlah transaksi yang tetap
+// tidak bisa dikonversi (tidak ikut dihitung di laporan). Dipanggil di setiap laporan, jadi
+// hanya baris bermata uang asing yang dibaca (index idx_transactions_user_currency).
+func EnsureRates(db *gorm.DB, userID uint, base string) int64 {
+	var missing []struct {
+		Currency string
+		Day      time.Time
+	}
+	db.Raw(`
+        SELECT DISTINCT t.currency, CAST(t.date AS date) AS day
+        FROM transactions t
+        WHERE t.user_id = ? AND t.currency <> ? AND `+ConvertSQL("1", "t.currency", "t.date", base)+` IS NULL
+    `, userID, base).Scan(&missing)
+	if len(missing) == 0 {
+		return 0
+	}
+
+	if Rates != nil {
+		for _, m := range missing {
+			rate, err := Rates.Rate(m.Currency, base, m.Day)
+			if err != nil {
+				continue
+			}
+			if err := SaveRate(db, ParsedRate{Date: m.Day, Base: m.Currency, Quote: base, Rate: rate}, Rates.Name()); err != nil {
+				log.Println("Gagal simpan kurs:", err)
+			}
+		}
+	}
+
+	var unconverted int64
+	db.Raw(`
+        SELECT COUNT(*) FROM transactions t
+        WHERE t.user_id = ? AND t.currency <> ? AND `+ConvertSQL("1", "t.currency", "t.date", base)+` IS NULL
+    `, userID, base).Scan(&unconverted)
+	return unconverted
+}
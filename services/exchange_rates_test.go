@@ -0,0 +1,140 @@
+// services/exchange_rates_test.go
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"finance/money"
+)
+
+func TestParseRatesCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want []ParsedRate
+		err  string
+	}{
+		{
+			name: "header and lowercase codes",
+			csv:  "date,base,quote,rate\n2024-01-31,usd,idr,15800.50\n",
+			want: []ParsedRate{{Date: day("2024-01-31"), Base: "USD", Quote: "IDR", Rate: "15800.5"}},
+		},
+		{
+			name: "no header, leading spaces",
+			csv:  "2024-02-01, EUR, USD, 1.0850\n2024-02-02,EUR,USD,1.0861\n",
+			want: []ParsedRate{
+				{Date: day("2024-02-01"), Base: "EUR", Quote: "USD", Rate: "1.085"},
+				{Date: day("2024-02-02"), Base: "EUR", Quote: "USD", Rate: "1.0861"},
+			},
+		},
+		{name: "bad date", csv: "31/01/2024,USD,IDR,15800\n", err: "line 1: invalid date"},
+		{name: "same currency", csv: "2024-01-31,USD,USD,1\n", err: "line 1: invalid currency pair"},
+		{name: "bad code", csv: "2024-01-31,US,IDR,1\n", err: "line 1: invalid currency pair"},
+		{name: "zero rate", csv: "date,base,quote,rate\n2024-01-31,USD,IDR,0\n", err: "line 2: invalid rate"},
+		{name: "float notation", csv: "2024-01-31,USD,IDR,1.58e4\n", err: "line 1: invalid rate"},
+		{name: "too precise", csv: "2024-01-31,IDR,USD,0.00006329113924\n", err: "line 1: invalid rate"},
+		{name: "wrong column count", csv: "2024-01-31,USD,IDR\n", err: "wrong number of fields"},
+	}
+	for _, tt := range tests {
+		got, err := ParseRatesCSV(strings.NewReader(tt.csv))
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %d rates, want %d", tt.name, len(got), len(tt.want))
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: rate %d = %+v, want %+v", tt.name, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestFileRateProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	csv := "date,base,quote,rate\n" +
+		"2024-01-10,USD,IDR,15600\n" +
+		"2024-01-01,USD,IDR,15500\n" + // urutan file tidak harus naik
+		"2024-01-20,USD,IDR,15700\n" +
+		"2024-01-15,EUR,USD,1.25\n"
+	if err := os.WriteFile(path, []byte(csv), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := LoadFileRateProvider(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Len() != 4 {
+		t.Errorf("Len = %d, want 4", p.Len())
+	}
+
+	tests := []struct {
+		name        string
+		base, quote string
+		date        string
+		want        money.Rate
+		err         error
+	}{
+		{"direct pair, exact date", "USD", "IDR", "2024-01-10", "15600", nil},
+		{"latest on or before", "USD", "IDR", "2024-01-19", "15600", nil},
+		{"time of day ignored", "USD", "IDR", "2024-01-20T23:30:00Z", "15700", nil},
+		{"after last rate", "USD", "IDR", "2025-06-01", "15700", nil},
+		{"inverse pair", "USD", "EUR", "2024-02-01", "0.8", nil},
+		{"inverse pair rounded", "IDR", "USD", "2024-01-10", "0.0000641026", nil},
+		{"before first rate", "USD", "IDR", "2023-12-31", "", ErrRateNotFound},
+		{"missing pair", "JPY", "IDR", "2024-01-10", "", ErrRateNotFound},
+	}
+	for _, tt := range tests {
+		date, err := time.Parse(time.RFC3339, tt.date)
+		if err != nil {
+			date = day(tt.date)
+		}
+		got, err := p.Rate(tt.base, tt.quote, date)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("%s: Rate(%s/%s, %s) = %q, %v; want %q, %v", tt.name, tt.base, tt.quote, tt.date, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestConvertSQL(t *testing.T) {
+	sql := ConvertSQL("t.amount", "t.currency", "t.date", "USD")
+	for _, want := range []string{
+		"WHEN t.currency = 'USD' THEN t.amount", // mata uang sama: tanpa konversi dan tanpa pembulatan
+		"ROUND(t.amount * (",                    // hasil konversi dibulatkan ...
+		"), 2) END",                             // ... ke 2 digit, sesuai decimal(15,2)
+		"THEN er.rate ELSE 1 / er.rate END",     // pasangan terbalik memakai 1/rate
+		"er.date <= CAST(t.date AS date)",       // kurs terakhir pada/sebelum tanggal
+		"ORDER BY er.date DESC LIMIT 1",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("ConvertSQL missing %q:\n%s", want, sql)
+		}
+	}
+
+	// base tidak valid tidak pernah ditulis ke SQL
+	if sql := ConvertSQL("t.amount", "t.currency", "t.date", "x'; DROP TABLE users; --"); strings.Contains(sql, "DROP") || !strings.Contains(sql, "'IDR'") {
+		t.Errorf("invalid base should fall back to IDR:\n%s", sql)
+	}
+}
+
+func day(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
@@ -0,0 +1,79 @@
+// services/login_attempt_store.go
+package services
+
+import (
+	"errors"
+	"time"
+
+	"finance/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DBAttemptStore menyimpan counter di tabel login_attempts sehingga berlaku lintas instance
+type DBAttemptStore struct {
+	DB *gorm.DB
+}
+
+func toState(a models.LoginAttempt) AttemptState {
+	return AttemptState{
+		Key:         a.Key,
+		Failures:    a.Failures,
+		Lockouts:    a.Lockouts,
+		LastFailure: a.LastFailureAt,
+		LockedUntil: a.LockedUntil,
+	}
+}
+
+func (d *DBAttemptStore) Get(key string) (AttemptState, error) {
+	var a models.LoginAttempt
+	err := d.DB.Where("attempt_key = ?", key).First(&a).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return AttemptState{Key: key}, nil
+	}
+	return toState(a), err
+}
+
+func (d *DBAttemptStore) Update(key string, fn func(s *AttemptState)) (AttemptState, error) {
+	var state AttemptState
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		// Pastikan baris ada lalu kunci agar kegagalan paralel tidak saling menimpa
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.LoginAttempt{Key: key}).Error; err != nil {
+			return err
+		}
+		var a models.LoginAttempt
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("attempt_key = ?", key).First(&a).Error; err != nil {
+			return err
+		}
+		state = toState(a)
+		fn(&state)
+		a.Failures = state.Failures
+		a.Lockouts = state.Lockouts
+		a.LastFailureAt = state.LastFailure
+		a.LockedUntil = state.LockedUntil
+		return tx.Save(&a).Error
+	})
+	return state, err
+}
+
+func (d *DBAttemptStore) Reset(key string) error {
+	return d.DB.Model(&models.LoginAttempt{}).Where("attempt_key = ?", key).Update("failures", 0).Error
+}
+
+func (d *DBAttemptStore) Prune(staleBefore, now time.Time) (int64, error) {
+	res := d.DB.Where("last_failure_at < ? AND locked_until <= ?", staleBefore, now).Delete(&models.LoginAttempt{})
+	return res.RowsAffected, res.Error
+}
+
+func (d *DBAttemptStore) List() ([]AttemptState, error) {
+	var rows []models.LoginAttempt
+	if err := d.DB.Order("updated_at desc").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	list := make([]AttemptState, 0, len(rows))
+	for _, a := range rows {
+		list = append(list, toState(a))
+	}
+	return list, nil
+}
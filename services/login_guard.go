@@ -0,0 +1,199 @@
+// services/login_guard.go
+package services
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AttemptState adalah counter percobaan login gagal untuk satu key ("account:<email>" / "ip:<addr>")
+type AttemptState struct {
+	Key         string    `json:"key"`
+	Failures    int       `json:"failures"`
+	Lockouts    int       `json:"lockouts"`
+	LastFailure time.Time `json:"last_failure"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// AttemptStore menyimpan counter percobaan login gagal
+type AttemptStore interface {
+	Get(key string) (AttemptState, error)
+	// Update membaca state, menerapkan fn, lalu menyimpannya secara atomik
+	Update(key string, fn func(s *AttemptState)) (AttemptState, error)
+	Reset(key string) error
+	List() ([]AttemptState, error)
+	// Prune menghapus counter yang gagal terakhir sebelum staleBefore dan tidak sedang dikunci
+	Prune(staleBefore, now time.Time) (int64, error)
+}
+
+// LoginPolicy mengatur backoff dan lockout
+type LoginPolicy struct {
+	FreeAttempts int           // gagal tanpa jeda sebelum backoff dimulai
+	BaseDelay    time.Duration // jeda setelah gagal ke-(FreeAttempts+1), lalu dikali 2
+	MaxDelay     time.Duration
+	LockAfter    int // jumlah gagal yang memicu lockout
+	LockDuration time.Duration
+	Window       time.Duration // counter direset bila tidak ada kegagalan selama Window
+}
+
+var (
+	AccountPolicy = LoginPolicy{FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: 5 * time.Minute, LockAfter: 10, LockDuration: 30 * time.Minute, Window: time.Hour}
+	IPPolicy      = LoginPolicy{FreeAttempts: 10, BaseDelay: time.Second, MaxDelay: 5 * time.Minute, LockAfter: 50, LockDuration: 30 * time.Minute, Window: time.Hour}
+)
+
+// RetryAfter mengembalikan berapa lama key harus menunggu sebelum boleh mencoba lagi
+func (p LoginPolicy) RetryAfter(s AttemptState, now time.Time) time.Duration {
+	if now.Before(s.LockedUntil) {
+		return s.LockedUntil.Sub(now)
+	}
+	if s.Failures <= p.FreeAttempts || now.Sub(s.LastFailure) > p.Window {
+		return 0
+	}
+	delay := p.BaseDelay << uint(min(s.Failures-p.FreeAttempts-1, 20))
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if wait := s.LastFailure.Add(delay).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// LoginGuard menerapkan LoginPolicy di atas sebuah AttemptStore
+type LoginGuard struct {
+	Store AttemptStore
+}
+
+var Guard *LoginGuard
+
+// InitLoginGuard memilih store lewat LOGIN_ATTEMPT_STORE=memory|database (default database)
+func InitLoginGuard(db *gorm.DB) {
+	if os.Getenv("LOGIN_ATTEMPT_STORE") == "memory" {
+		Guard = &LoginGuard{Store: NewMemoryAttemptStore()}
+		return
+	}
+	Guard = &LoginGuard{Store: &DBAttemptStore{DB: db}}
+}
+
+// Blocked mengembalikan jeda terlama dari semua key; 0 berarti boleh mencoba
+func (g *LoginGuard) Blocked(policy LoginPolicy, key string) time.Duration {
+	s, err := g.Store.Get(key)
+	if err != nil {
+		return 0
+	}
+	return policy.RetryAfter(s, time.Now())
+}
+
+// Fail mencatat satu kegagalan. lockedNow true bila kegagalan ini memicu lockout baru.
+func (g *LoginGuard) Fail(policy LoginPolicy, key string) (state AttemptState, lockedNow bool, err error) {
+	now := time.Now()
+	state, err = g.Store.Update(key, func(s *AttemptState) {
+		if now.Sub(s.LastFailure) > policy.Window && now.After(s.LockedUntil) {
+			s.Failures = 0
+		}
+		s.Failures++
+		s.LastFailure = now
+		if s.Failures >= policy.LockAfter && now.After(s.LockedUntil) {
+			s.LockedUntil = now.Add(policy.LockDuration)
+			s.Lockouts++
+			s.Failures = 0
+			lockedNow = true
+		}
+	})
+	return state, lockedNow, err
+}
+
+func (g *LoginGuard) Succeed(key string) error {
+	return g.Store.Reset(key)
+}
+
+// loginAttemptRetention: counter tanpa kegagalan baru selama ini (dan tidak sedang dikunci)
+// dihapus agar store tidak tumbuh tanpa batas oleh IP/email yang hanya sekali mencoba
+const loginAttemptRetention = 24 * time.Hour
+
+// Prune menghapus counter yang sudah tidak berpengaruh pada backoff maupun lockout
+func (g *LoginGuard) Prune(now time.Time) (int64, error) {
+	return g.Store.Prune(now.Add(-loginAttemptRetention), now)
+}
+
+// StartLoginAttemptPruner menjalankan Guard.Prune secara berkala di background
+func StartLoginAttemptPruner(interval time.Duration) {
+	go func() {
+		for {
+			if n, err := Guard.Prune(time.Now()); err != nil {
+				log.Println("Gagal hapus login attempt:", err)
+			} else if n > 0 {
+				log.Printf("%d login attempt kedaluwarsa dihapus", n)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// MemoryAttemptStore cocok untuk satu instance / development; hilang saat restart
+type MemoryAttemptStore struct {
+	mu     sync.Mutex
+	states map[string]AttemptState
+}
+
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{states: map[string]AttemptState{}}
+}
+
+func (m *MemoryAttemptStore) Get(key string) (AttemptState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.states[key]
+	if !ok {
+		s.Key = key
+	}
+	return s, nil
+}
+
+func (m *MemoryAttemptStore) Update(key string, fn func(s *AttemptState)) (AttemptState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.states[key]
+	s.Key = key
+	fn(&s)
+	m.states[key] = s
+	return s, nil
+}
+
+func (m *MemoryAttemptStore) Reset(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Lockout yang sedang berjalan dan jumlah lockout tetap dipertahankan
+	if s, ok := m.states[key]; ok {
+		s.Failures = 0
+		m.states[key] = s
+	}
+	return nil
+}
+
+func (m *MemoryAttemptStore) Prune(staleBefore, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for key, s := range m.states {
+		if s.LastFailure.Before(staleBefore) && !now.Before(s.LockedUntil) {
+			delete(m.states, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemoryAttemptStore) List() ([]AttemptState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]AttemptState, 0, len(m.states))
+	for _, s := range m.states {
+		list = append(list, s)
+	}
+	return list, nil
+}
@@ -0,0 +1,58 @@
+// services/login_guard_test.go
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryAttemptStorePrune(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryAttemptStore()
+	set := func(key string, lastFailure, lockedUntil time.Time) {
+		store.Update(key, func(s *AttemptState) {
+			s.Failures = 1
+			s.LastFailure = lastFailure
+			s.LockedUntil = lockedUntil
+		})
+	}
+	set("ip:recent", now.Add(-time.Hour), time.Time{})
+	set("ip:stale", now.Add(-25*time.Hour), time.Time{})
+	set("account:stale-but-locked", now.Add(-25*time.Hour), now.Add(time.Minute))
+	set("account:lock-expired", now.Add(-48*time.Hour), now.Add(-47*time.Hour))
+
+	g := &LoginGuard{Store: store}
+	n, err := g.Prune(now)
+	if err != nil || n != 2 {
+		t.Fatalf("Prune = %d, %v; want 2", n, err)
+	}
+	list, _ := store.List()
+	kept := map[string]bool{}
+	for _, s := range list {
+		kept[s.Key] = true
+	}
+	if len(kept) != 2 || !kept["ip:recent"] || !kept["account:stale-but-locked"] {
+		t.Errorf("kept %v, want ip:recent and account:stale-but-locked", kept)
+	}
+}
+
+func TestLoginGuardLockout(t *testing.T) {
+	g := &LoginGuard{Store: NewMemoryAttemptStore()}
+	policy := LoginPolicy{FreeAttempts: 1, BaseDelay: time.Second, MaxDelay: time.Minute, LockAfter: 3, LockDuration: time.Hour, Window: time.Hour}
+	for i := 1; i <= 3; i++ {
+		_, locked, err := g.Fail(policy, "account:a@b.c")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if locked != (i == 3) {
+			t.Errorf("failure %d: lockedNow = %v", i, locked)
+		}
+	}
+	if wait := g.Blocked(policy, "account:a@b.c"); wait < 59*time.Minute {
+		t.Errorf("Blocked = %s, want about an hour", wait)
+	}
+	// sehari kemudian counter sudah kedaluwarsa dan dihapus
+	if n, _ := g.Prune(time.Now().Add(25 * time.Hour)); n != 1 {
+		t.Errorf("Prune after a day removed %d keys, want 1", n)
+	}
+}
@@ -0,0 +1,130 @@
+// services/recurrence.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"finance/models"
+)
+
+const (
+	RecurDaily   = "daily"
+	RecurWeekly  = "weekly"
+	RecurMonthly = "monthly"
+
+	// recurrenceSearchLimit membatasi pencarian nomor kemunculan dari sebuah tanggal
+	recurrenceSearchLimit = 100000
+)
+
+var (
+	ErrInvalidRecurrence = errors.New("invalid recurrence")
+	ErrNotAnOccurrence   = errors.New("date is not an occurrence of this series")
+)
+
+// ValidateRecurrence memeriksa aturan jadwal lalu menggeser StartDate ke kemunculan pertama
+// bila MonthDay membuat tanggal di bulan StartDate jatuh sebelum StartDate.
+func ValidateRecurrence(r *models.Recurrence) error {
+	switch r.Frequency {
+	case RecurDaily, RecurWeekly, RecurMonthly:
+	default:
+		return fmt.Errorf("%w: frequency must be daily, weekly or monthly", ErrInvalidRecurrence)
+	}
+	if r.Interval < 1 || r.Interval > 366 {
+		return fmt.Errorf("%w: interval must be between 1 and 366", ErrInvalidRecurrence)
+	}
+	if r.Frequency == RecurMonthly {
+		if r.MonthDay < -1 || r.MonthDay > 31 {
+			return fmt.Errorf("%w: month_day must be 1..31, -1 (end of month) or 0", ErrInvalidRecurrence)
+		}
+	} else {
+		r.MonthDay = 0
+	}
+	if r.Count < 0 {
+		return fmt.Errorf("%w: count cannot be negative", ErrInvalidRecurrence)
+	}
+	if r.StartDate.IsZero() {
+		return fmt.Errorf("%w: start_date is required", ErrInvalidRecurrence)
+	}
+	if first := OccurrenceDate(r, 0); first.Before(r.StartDate) {
+		r.StartDate = OccurrenceDate(r, 1)
+	} else {
+		r.StartDate = first
+	}
+	if r.Until != nil && truncateDay(*r.Until).Before(truncateDay(r.StartDate)) {
+		return fmt.Errorf("%w: until must not be before the first occurrence", ErrInvalidRecurrence)
+	}
+	return nil
+}
+
+// OccurrenceDate menghitung tanggal kemunculan ke-n (mulai 0) langsung dari StartDate,
+// sehingga tanggal bulanan tidak bergeser (31 Jan -> 28/29 Feb -> 31 Mar).
+func OccurrenceDate(r *models.Recurrence, n int) time.Time {
+	s := r.StartDate
+	switch r.Frequency {
+	case RecurDaily:
+		return s.AddDate(0, 0, n*r.Interval)
+	case RecurWeekly:
+		return s.AddDate(0, 0, 7*n*r.Interval)
+	}
+	first := time.Date(s.Year(), s.Month()+time.Month(n*r.Interval), 1, s.Hour(), s.Minute(), s.Second(), 0, s.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	day := r.MonthDay
+	if day == 0 {
+		day = s.Day()
+	}
+	if day < 0 || day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// recurrenceEnded: kemunculan ke-n (bertanggal date) sudah di luar Count/Until
+func recurrenceEnded(r *models.Recurrence, n int, date time.Time) bool {
+	if r.Count > 0 && n >= r.Count {
+		return true
+	}
+	return r.Until != nil && truncateDay(date).After(truncateDay(*r.Until))
+}
+
+// NextRunFor mengembalikan tanggal kemunculan ke-n, atau nil bila seri sudah selesai
+func NextRunFor(r *models.Recurrence, n int) *time.Time {
+	date := OccurrenceDate(r, n)
+	if recurrenceEnded(r, n, date) {
+		return nil
+	}
+	return &date
+}
+
+// OccurrenceIndex mencari nomor kemunculan untuk tanggal day (dibandingkan per hari)
+func OccurrenceIndex(r *models.Recurrence, day time.Time) (int, error) {
+	target := truncateDay(day)
+	for n := 0; n < recurrenceSearchLimit; n++ {
+		date := OccurrenceDate(r, n)
+		if recurrenceEnded(r, n, date) {
+			break
+		}
+		d := truncateDay(date)
+		if d.Equal(target) {
+			return n, nil
+		}
+		if d.After(target) {
+			break
+		}
+	}
+	return 0, ErrNotAnOccurrence
+}
+
+// FirstIndexAfter mengembalikan nomor kemunculan pertama yang tanggalnya setelah day;
+// dipakai saat jadwal seri diubah agar kemunculan yang sudah diproses tidak dibuat ulang.
+func FirstIndexAfter(r *models.Recurrence, day time.Time) int {
+	target := truncateDay(day)
+	n := 0
+	for ; n < recurrenceSearchLimit; n++ {
+		if truncateDay(OccurrenceDate(r, n)).After(target) {
+			break
+		}
+	}
+	return n
+}
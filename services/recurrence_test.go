@@ -0,0 +1,222 @@
+// services/recurrence_test.go
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"finance/models"
+)
+
+func at(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func dates(r *models.Recurrence, n int) []string {
+	var out []string
+	for i := 0; i < n; i++ {
+		out = append(out, OccurrenceDate(r, i).Format("2006-01-02 15:04"))
+	}
+	return out
+}
+
+func TestOccurrenceDate(t *testing.T) {
+	tests := []struct {
+		name string
+		r    models.Recurrence
+		want []string
+	}{
+		{
+			name: "daily keeps time of day",
+			r:    models.Recurrence{Frequency: RecurDaily, Interval: 1, StartDate: at("2024-02-28 09:30")},
+			want: []string{"2024-02-28 09:30", "2024-02-29 09:30", "2024-03-01 09:30"},
+		},
+		{
+			name: "every 3 days",
+			r:    models.Recurrence{Frequency: RecurDaily, Interval: 3, StartDate: at("2024-12-30 00:00")},
+			want: []string{"2024-12-30 00:00", "2025-01-02 00:00", "2025-01-05 00:00"},
+		},
+		{
+			name: "biweekly",
+			r:    models.Recurrence{Frequency: RecurWeekly, Interval: 2, StartDate: at("2024-01-05 08:00")},
+			want: []string{"2024-01-05 08:00", "2024-01-19 08:00", "2024-02-02 08:00"},
+		},
+		{
+			name: "monthly on the 31st clamps without drifting",
+			r:    models.Recurrence{Frequency: RecurMonthly, Interval: 1, StartDate: at("2024-01-31 07:00")},
+			want: []string{"2024-01-31 07:00", "2024-02-29 07:00", "2024-03-31 07:00", "2024-04-30 07:00"},
+		},
+		{
+			name: "month_day 30 in non-leap february",
+			r:    models.Recurrence{Frequency: RecurMonthly, Interval: 1, MonthDay: 30, StartDate: at("2025-01-30 00:00")},
+			want: []string{"2025-01-30 00:00", "2025-02-28 00:00", "2025-03-30 00:00"},
+		},
+		{
+			name: "month_day -1 is always the last day",
+			r:    models.Recurrence{Frequency: RecurMonthly, Interval: 1, MonthDay: -1, StartDate: at("2024-01-31 00:00")},
+			want: []string{"2024-01-31 00:00", "2024-02-29 00:00", "2024-03-31 00:00", "2024-04-30 00:00"},
+		},
+		{
+			name: "quarterly across year end",
+			r:    models.Recurrence{Frequency: RecurMonthly, Interval: 3, MonthDay: 15, StartDate: at("2024-11-15 12:00")},
+			want: []string{"2024-11-15 12:00", "2025-02-15 12:00", "2025-05-15 12:00"},
+		},
+	}
+	for _, tt := range tests {
+		got := dates(&tt.r, len(tt.want))
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: occurrence %d = %s, want %s", tt.name, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestValidateRecurrence(t *testing.T) {
+	tests := []struct {
+		name      string
+		r         models.Recurrence
+		wantStart string
+		ok        bool
+	}{
+		{"month_day later in the same month", models.Recurrence{Frequency: RecurMonthly, Interval: 1, MonthDay: 25, StartDate: at("2024-03-10 00:00")}, "2024-03-25 00:00", true},
+		{"month_day already passed moves to next month", models.Recurrence{Frequency: RecurMonthly, Interval: 1, MonthDay: 5, StartDate: at("2024-03-10 00:00")}, "2024-04-05 00:00", true},
+		{"end of month", models.Recurrence{Frequency: RecurMonthly, Interval: 2, MonthDay: -1, StartDate: at("2024-02-10 00:00")}, "2024-02-29 00:00", true},
+		{"weekly ignores month_day", models.Recurrence{Frequency: RecurWeekly, Interval: 1, MonthDay: 9, StartDate: at("2024-03-10 00:00")}, "2024-03-10 00:00", true},
+		{"bad frequency", models.Recurrence{Frequency: "yearly", Interval: 1, StartDate: at("2024-03-10 00:00")}, "", false},
+		{"zero interval", models.Recurrence{Frequency: RecurDaily, StartDate: at("2024-03-10 00:00")}, "", false},
+		{"month_day 32", models.Recurrence{Frequency: RecurMonthly, Interval: 1, MonthDay: 32, StartDate: at("2024-03-10 00:00")}, "", false},
+		{"negative count", models.Recurrence{Frequency: RecurDaily, Interval: 1, Count: -1, StartDate: at("2024-03-10 00:00")}, "", false},
+		{"missing start", models.Recurrence{Frequency: RecurDaily, Interval: 1}, "", false},
+	}
+	for _, tt := range tests {
+		r := tt.r
+		err := ValidateRecurrence(&r)
+		if tt.ok != (err == nil) {
+			t.Errorf("%s: error = %v, want ok=%v", tt.name, err, tt.ok)
+			continue
+		}
+		if !tt.ok {
+			if !errors.Is(err, ErrInvalidRecurrence) {
+				t.Errorf("%s: error %v is not ErrInvalidRecurrence", tt.name, err)
+			}
+			continue
+		}
+		if got := r.StartDate.Format("2006-01-02 15:04"); got != tt.wantStart {
+			t.Errorf("%s: start = %s, want %s", tt.name, got, tt.wantStart)
+		}
+	}
+
+	until := at("2024-03-20 00:00")
+	r := models.Recurrence{Frequency: RecurMonthly, Interval: 1, MonthDay: 25, StartDate: at("2024-03-21 00:00"), Until: &until}
+	if err := ValidateRecurrence(&r); !errors.Is(err, ErrInvalidRecurrence) {
+		t.Errorf("until before first occurrence: error = %v", err)
+	}
+}
+
+func TestNextRunFor(t *testing.T) {
+	until := at("2024-03-31 00:00")
+	tests := []struct {
+		name string
+		r    models.Recurrence
+		n    int
+		want string // "" = seri selesai
+	}{
+		{"no limits", models.Recurrence{Frequency: RecurMonthly, Interval: 1, StartDate: at("2024-01-31 06:00")}, 12, "2025-01-31 06:00"},
+		{"last counted occurrence", models.Recurrence{Frequency: RecurDaily, Interval: 1, Count: 3, StartDate: at("2024-01-01 06:00")}, 2, "2024-01-03 06:00"},
+		{"count reached", models.Recurrence{Frequency: RecurDaily, Interval: 1, Count: 3, StartDate: at("2024-01-01 06:00")}, 3, ""},
+		{"until is inclusive, time of day ignored", models.Recurrence{Frequency: RecurMonthly, Interval: 1, MonthDay: -1, StartDate: at("2024-01-31 23:00"), Until: &until}, 2, "2024-03-31 23:00"},
+		{"past until", models.Recurrence{Frequency: RecurMonthly, Interval: 1, MonthDay: -1, StartDate: at("2024-01-31 23:00"), Until: &until}, 3, ""},
+		{"count and until, until first", models.Recurrence{Frequency: RecurWeekly, Interval: 2, Count: 10, StartDate: at("2024-03-01 00:00"), Until: &until}, 3, ""},
+	}
+	for _, tt := range tests {
+		got := NextRunFor(&tt.r, tt.n)
+		switch {
+		case tt.want == "" && got != nil:
+			t.Errorf("%s: NextRunFor(%d) = %s, want end of series", tt.name, tt.n, got)
+		case tt.want != "" && (got == nil || got.Format("2006-01-02 15:04") != tt.want):
+			t.Errorf("%s: NextRunFor(%d) = %v, want %s", tt.name, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestOccurrenceIndex(t *testing.T) {
+	r := models.Recurrence{Frequency: RecurMonthly, Interval: 1, MonthDay: -1, Count: 4, StartDate: at("2024-01-31 09:00")}
+	tests := []struct {
+		day  string
+		want int
+		err  error
+	}{
+		{"2024-01-31 00:00", 0, nil},
+		{"2024-02-29 18:45", 1, nil}, // dibandingkan per hari
+		{"2024-04-30 00:00", 3, nil},
+		{"2024-02-28 00:00", 0, ErrNotAnOccurrence},
+		{"2024-05-31 00:00", 0, ErrNotAnOccurrence}, // di luar Count
+		{"2023-12-31 00:00", 0, ErrNotAnOccurrence},
+	}
+	for _, tt := range tests {
+		got, err := OccurrenceIndex(&r, at(tt.day))
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("OccurrenceIndex(%s) = %d, %v; want %d, %v", tt.day, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestFirstIndexAfter(t *testing.T) {
+	monthly := models.Recurrence{Frequency: RecurMonthly, Interval: 1, StartDate: at("2024-01-15 10:00")}
+	tests := []struct {
+		name string
+		r    models.Recurrence
+		day  string
+		want int
+	}{
+		{"before start", monthly, "2024-01-01 00:00", 0},
+		{"on an occurrence day skips it", monthly, "2024-03-15 23:59", 3},
+		{"between occurrences", monthly, "2024-03-20 00:00", 3},
+		{"daily", models.Recurrence{Frequency: RecurDaily, Interval: 2, StartDate: at("2024-01-01 00:00")}, "2024-01-04 00:00", 2},
+	}
+	for _, tt := range tests {
+		if got := FirstIndexAfter(&tt.r, at(tt.day)); got != tt.want {
+			t.Errorf("%s: FirstIndexAfter(%s) = %d, want %d", tt.name, tt.day, got, tt.want)
+		}
+	}
+}
+
+// Mengubah aturan seri: kemunculan baru dinomori ulang dari tanggal terakhir yang sudah diproses,
+// sehingga tidak ada tanggal yang dibuat dua kali atau terlewat.
+func TestRenumberAfterRuleChange(t *testing.T) {
+	old := models.Recurrence{Frequency: RecurMonthly, Interval: 1, MonthDay: 10, StartDate: at("2024-01-10 08:00")}
+	lastDone := OccurrenceDate(&old, 2) // 10 Maret sudah dibuat
+
+	changed := old
+	changed.MonthDay = -1
+	if err := ValidateRecurrence(&changed); err != nil {
+		t.Fatal(err)
+	}
+	n := FirstIndexAfter(&changed, lastDone)
+	next := NextRunFor(&changed, n)
+	if next == nil || next.Format("2006-01-02 15:04") != "2024-03-31 08:00" {
+		t.Fatalf("next after change = %v (index %d), want 2024-03-31 08:00", next, n)
+	}
+
+	// aturan yang membuat tanggal berikutnya jatuh di hari yang sama tidak mengulang hari itu
+	sameDay := old
+	sameDay.Interval = 2
+	n = FirstIndexAfter(&sameDay, lastDone)
+	if d := OccurrenceDate(&sameDay, n); !truncateDay(d).After(truncateDay(lastDone)) {
+		t.Errorf("renumbered occurrence %s is not after %s", d, lastDone)
+	}
+
+	// dengan Count, nomor urut lama tetap dihitung: seri 4 kali yang sudah 3 kali tinggal sekali
+	counted := old
+	counted.Count = 4
+	n = FirstIndexAfter(&counted, lastDone)
+	if NextRunFor(&counted, n) == nil || NextRunFor(&counted, n+1) != nil {
+		t.Errorf("count 4 after 3 occurrences: index %d should be the last one", n)
+	}
+}
@@ -0,0 +1,195 @@
+// services/recurring.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"finance/models"
+	"finance/money"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recurringCatchUpLimit membatasi jumlah kemunculan yang dibuat per template per putaran worker
+const recurringCatchUpLimit = 500
+
+var errOccurrenceClaimed = errors.New("occurrence already claimed")
+
+// PlannedOccurrence adalah satu kemunculan pada preview jadwal
+type PlannedOccurrence struct {
+	Index      int          `json:"index"`
+	Date       time.Time    `json:"date"`
+	Status     string       `json:"status"` // "scheduled", "pending" (diubah khusus tanggal ini) atau "skipped"
+	CategoryID uint         `json:"category_id"`
+	Amount     money.Amount `json:"amount"`
+	Note       string       `json:"note"`
+}
+
+// PreviewRecurring menghitung hingga limit kemunculan berikutnya mulai dari NextIndex,
+// dengan perubahan per kemunculan (skip/edit) yang sudah tersimpan.
+func PreviewRecurring(db *gorm.DB, r *models.RecurringTransaction, limit int) []PlannedOccurrence {
+	overrides := map[time.Time]models.RecurringOccurrence{}
+	if r.ID != 0 {
+		var rows []models.RecurringOccurrence
+		db.Where("recurring_id = ? AND status IN ?", r.ID, []string{models.OccurrencePending, models.OccurrenceSkipped}).Find(&rows)
+		for _, o := range rows {
+			overrides[truncateDay(o.Date)] = o
+		}
+	}
+
+	planned := []PlannedOccurrence{}
+	for n := r.NextIndex; len(planned) < limit; n++ {
+		date := OccurrenceDate(&r.Recurrence, n)
+		if recurrenceEnded(&r.Recurrence, n, date) {
+			break
+		}
+		p := PlannedOccurrence{Index: n, Date: date, Status: "scheduled", CategoryID: r.CategoryID, Amount: r.Amount, Note: r.Note}
+		if o, ok := overrides[truncateDay(date)]; ok {
+			p.Status = o.Status
+			if o.CategoryID != nil {
+				p.CategoryID = *o.CategoryID
+			}
+			if o.Amount != nil {
+				p.Amount = *o.Amount
+			}
+			if o.Note != nil {
+				p.Note = *o.Note
+			}
+		}
+		planned = append(planned, p)
+	}
+	return planned
+}
+
+// materializeOccurrence membuat transaksi untuk satu kemunculan, lewat jalur yang sama dengan
+// POST /transactions. Kemunculan yang sudah dibuat/di-skip dilewati (idempoten).
+func materializeOccurrence(tx *gorm.DB, r *models.RecurringTransaction, date time.Time) error {
+	occ := models.RecurringOccurrence{RecurringID: r.ID, UserID: r.UserID, Date: truncateDay(date)}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("recurring_id = ? AND date = ?", r.ID, occ.Date.Format("2006-01-02")).First(&occ).Error
+	if err == nil && occ.Status != models.OccurrencePending {
+		return nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	in := NewTransaction{
+		CategoryID: r.CategoryID,
+		AccountID:  r.AccountID,
+		Amount:     r.Amount,
+		Date:       date,
+		Note:       r.Note,
+		Currency:   r.Currency,
+	}
+	if occ.CategoryID != nil {
+		in.CategoryID = *occ.CategoryID
+	}
+	if occ.Amount != nil {
+		in.Amount = *occ.Amount
+	}
+	if occ.Note != nil {
+		in.Note = *occ.Note
+	}
+
+	trx, cat, err := BuildTransaction(tx, r.UserID, in)
+	if err != nil {
+		// mis. kategori/akun sudah dihapus: catat gagal dan beri tahu user, seri tetap berjalan
+		occ.Status = models.OccurrenceFailed
+		occ.Error = err.Error()
+		notif := models.Notification{
+			UserID:    r.UserID,
+			Title:     "Recurring Transaction Failed",
+			Message:   fmt.Sprintf("Transaksi berulang %q tanggal %s gagal dibuat: %v", r.Note, date.Format("02 Jan 2006"), err),
+			CreatedAt: time.Now(),
+		}
+		if err := tx.Create(&notif).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RecurringTransaction{}).Where("id = ?", r.ID).Update("last_error", occ.Error).Error; err != nil {
+			return err
+		}
+		return tx.Save(&occ).Error
+	}
+
+	trx.RecurringID = &r.ID
+	if err := CreateTransaction(tx, trx, nil); err != nil {
+		return err
+	}
+	occ.Status = models.OccurrenceCreated
+	occ.TransactionID = &trx.ID
+	occ.Error = ""
+	if err := tx.Save(&occ).Error; err != nil {
+		return err
+	}
+	CheckCategoryBudget(tx, r.UserID, *cat)
+	return nil
+}
+
+// MaterializeDue membuat semua kemunculan template yang sudah jatuh tempo pada now, termasuk
+// yang terlewat saat server mati (catch-up). Setiap kemunculan diklaim dengan menggeser
+// next_index secara atomik, sehingga aman dijalankan beberapa instance sekaligus.
+func MaterializeDue(db *gorm.DB, r models.RecurringTransaction, now time.Time) (int, error) {
+	created := 0
+	for i := 0; i < recurringCatchUpLimit; i++ {
+		date := OccurrenceDate(&r.Recurrence, r.NextIndex)
+		if recurrenceEnded(&r.Recurrence, r.NextIndex, date) {
+			// seri selesai
+			return created, db.Model(&models.RecurringTransaction{}).
+				Where("id = ? AND next_index = ?", r.ID, r.NextIndex).Update("next_run_at", nil).Error
+		}
+		if date.After(now) {
+			break
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			res := tx.Model(&models.RecurringTransaction{}).
+				Where("id = ? AND next_index = ?", r.ID, r.NextIndex).
+				Updates(map[string]interface{}{"next_index": r.NextIndex + 1, "next_run_at": NextRunFor(&r.Recurrence, r.NextIndex+1)})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errOccurrenceClaimed
+			}
+			return materializeOccurrence(tx, &r, date)
+		})
+		if errors.Is(err, errOccurrenceClaimed) {
+			return created, nil
+		}
+		if err != nil {
+			return created, err
+		}
+		created++
+		r.NextIndex++
+	}
+	return created, nil
+}
+
+// RunDueRecurring memproses semua template yang punya kemunculan jatuh tempo
+func RunDueRecurring(db *gorm.DB) {
+	now := time.Now()
+	var due []models.RecurringTransaction
+	if err := db.Where("next_run_at <= ?", now).Find(&due).Error; err != nil {
+		log.Println("Gagal ambil transaksi berulang:", err)
+		return
+	}
+	for _, r := range due {
+		if _, err := MaterializeDue(db, r, now); err != nil {
+			log.Printf("Transaksi berulang %d gagal: %v", r.ID, err)
+		}
+	}
+}
+
+// StartRecurringWorker membuat transaksi berulang yang jatuh tempo secara berkala di background
+func StartRecurringWorker(db *gorm.DB, interval time.Duration) {
+	go func() {
+		for {
+			RunDueRecurring(db)
+			time.Sleep(interval)
+		}
+	}()
+}
@@ -0,0 +1,82 @@
+// services/split_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"finance/models"
+	"finance/money"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidSplit = errors.New("invalid split")
+
+// CategoryLinesSQL memecah transaksi menjadi baris per kategori: transaksi split menghasilkan
+// satu baris per split line, transaksi biasa satu baris dengan kategori dan jumlahnya sendiri.
+// Pakai sebagai subquery: FROM `+CategoryLinesSQL+` tl
+const CategoryLinesSQL = `(
+            SELECT t.id AS transaction_id, t.user_id, t.date, t.transfer_id, t.account_id, t.currency,
+                   COALESCE(s.category_id, t.category_id) AS category_id,
+                   COALESCE(s.amount, t.amount) AS amount
+            FROM transactions t
+            LEFT JOIN transaction_splits s ON s.transaction_id = t.id
+        )`
+
+// SplitLine adalah payload satu baris split dari API
+type SplitLine struct {
+	CategoryID uint         `json:"category_id"`
+	Amount     money.Amount `json:"amount"`
+	Note       string       `json:"note"`
+}
+
+// ValidateSplits memastikan semua kategori milik user dan bertipe sama, jumlah tiap baris > 0,
+// dan total baris sama dengan jumlah transaksi. Mengembalikan kategori baris pertama
+// yang dipakai sebagai category_id transaksi.
+func ValidateSplits(db *gorm.DB, userID uint, total money.Amount, lines []SplitLine) (*models.Category, error) {
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("%w: at least 2 lines required", ErrInvalidSplit)
+	}
+	var first *models.Category
+	var sum money.Amount
+	for i, l := range lines {
+		if l.Amount <= 0 {
+			return nil, fmt.Errorf("%w: line %d amount must be greater than 0", ErrInvalidSplit, i+1)
+		}
+		var cat models.Category
+		if err := db.Where("id = ? AND user_id = ?", l.CategoryID, userID).First(&cat).Error; err != nil {
+			return nil, fmt.Errorf("%w: line %d has invalid category", ErrInvalidSplit, i+1)
+		}
+		if first == nil {
+			first = &cat
+		} else if cat.Type != first.Type {
+			return nil, fmt.Errorf("%w: all lines must be %s categories", ErrInvalidSplit, first.Type)
+		}
+		sum += l.Amount
+	}
+	if sum != total {
+		return nil, fmt.Errorf("%w: lines sum to %s, expected %s", ErrInvalidSplit, sum, total)
+	}
+	return first, nil
+}
+
+// ReplaceSplits mengganti semua split line transaksi. lines kosong = transaksi tidak di-split lagi.
+func ReplaceSplits(tx *gorm.DB, trx *models.Transaction, lines []SplitLine) error {
+	if err := tx.Where("transaction_id = ?", trx.ID).Delete(&models.TransactionSplit{}).Error; err != nil {
+		return err
+	}
+	for _, l := range lines {
+		split := models.TransactionSplit{
+			TransactionID: trx.ID,
+			UserID:        trx.UserID,
+			CategoryID:    l.CategoryID,
+			Amount:        l.Amount,
+			Note:          l.Note,
+		}
+		if err := tx.Create(&split).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
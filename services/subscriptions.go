@@ -0,0 +1,263 @@
+// services/subscriptions.go
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"finance/models"
+	"finance/money"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// subscriptionLookback: riwayat transaksi yang dianalisis
+	subscriptionLookbackMonths = 25
+	// subscriptionRegularShare: minimal 3/4 jarak antar transaksi harus sesuai cadence
+	subscriptionRegularShare = 0.75
+)
+
+// subscriptionCadence adalah pola jarak yang dikenali beserta padanannya di template recurring
+type subscriptionCadence struct {
+	Name      string
+	MinDays   int // toleransi jarak antar transaksi (hari)
+	MaxDays   int
+	PerYear   int64
+	MinCount  int // minimal jumlah transaksi agar dianggap langganan
+	Frequency string
+	Interval  int
+}
+
+var subscriptionCadences = []subscriptionCadence{
+	{Name: "weekly", MinDays: 6, MaxDays: 8, PerYear: 52, MinCount: 4, Frequency: RecurWeekly, Interval: 1},
+	{Name: "biweekly", MinDays: 12, MaxDays: 16, PerYear: 26, MinCount: 3, Frequency: RecurWeekly, Interval: 2},
+	{Name: "monthly", MinDays: 26, MaxDays: 35, PerYear: 12, MinCount: 3, Frequency: RecurMonthly, Interval: 1},
+	{Name: "quarterly", MinDays: 84, MaxDays: 98, PerYear: 4, MinCount: 3, Frequency: RecurMonthly, Interval: 3},
+	{Name: "yearly", MinDays: 350, MaxDays: 380, PerYear: 1, MinCount: 2, Frequency: RecurMonthly, Interval: 12},
+}
+
+// SuspectedSubscription adalah pengeluaran berulang hasil deteksi dari riwayat transaksi
+type SuspectedSubscription struct {
+	Key              string       `json:"key"`     // id stabil untuk confirm/dismiss
+	Pattern          string       `json:"pattern"` // catatan yang dinormalisasi
+	Name             string       `json:"name"`    // catatan transaksi terakhir
+	Currency         string       `json:"currency"`
+	CategoryID       uint         `json:"category_id"`
+	AccountID        *uint        `json:"account_id"`
+	Cadence          string       `json:"cadence"` // weekly, biweekly, monthly, quarterly, yearly
+	Occurrences      int          `json:"occurrences"`
+	AverageAmount    money.Amount `json:"average_amount"`
+	LastAmount       money.Amount `json:"last_amount"`
+	LastDate         string       `json:"last_date"`
+	NextExpectedDate string       `json:"next_expected_date"`
+	AnnualizedCost   money.Amount `json:"annualized_cost"`
+	TransactionIDs   []uint       `json:"transaction_ids"`
+	Dismissed        bool         `json:"dismissed"`
+
+	// Recurrence adalah usulan jadwal template recurring (mulai dari NextExpectedDate)
+	Recurrence models.Recurrence `json:"-"`
+}
+
+type subscriptionRow struct {
+	ID         uint
+	Date       time.Time
+	Amount     money.Amount
+	Currency   string
+	Note       string
+	CategoryID uint
+	AccountID  *uint
+}
+
+// NormalizeSubscriptionNote menyamakan catatan seperti "NETFLIX.COM 10/2026 #8812" dan
+// "Netflix.com 11/2026" menjadi "netflix com": huruf kecil, angka dan tanda baca dibuang.
+func NormalizeSubscriptionNote(note string) string {
+	fields := strings.FieldsFunc(strings.ToLower(note), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// SubscriptionKey adalah id stabil untuk satu pola (catatan + mata uang)
+func SubscriptionKey(pattern, currency string) string {
+	sum := sha256.Sum256([]byte(pattern + "|" + currency))
+	return hex.EncodeToString(sum[:8])
+}
+
+// DetectSubscriptions mencari pengeluaran dengan catatan serupa, nominal mirip dan jarak teratur.
+// Transaksi yang sudah dibuat template recurring atau pembayaran tagihan tidak ikut dianalisis,
+// sehingga langganan yang sudah dikonfirmasi tidak muncul lagi. Langganan yang tampaknya sudah
+// berhenti (tidak ada transaksi selama lebih dari dua periode) juga dilewati.
+func DetectSubscriptions(db *gorm.DB, userID uint, now time.Time) ([]SuspectedSubscription, error) {
+	var rows []subscriptionRow
+	err := db.Raw(`
+        SELECT t.id, t.date, t.amount, t.currency, t.note, t.category_id, t.account_id
+        FROM transactions t
+        JOIN categories c ON c.id = t.category_id
+        WHERE t.user_id = ? AND c.type = 'expense' AND t.recurring_id IS NULL AND t.date >= ?
+          AND NOT EXISTS (SELECT 1 FROM bill_payments bp WHERE bp.transaction_id = t.id)
+        ORDER BY t.date, t.id
+    `, userID, now.AddDate(0, -subscriptionLookbackMonths, 0)).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string][]subscriptionRow{}
+	var keys []string
+	for _, r := range rows {
+		pattern := NormalizeSubscriptionNote(r.Note)
+		if pattern == "" {
+			continue
+		}
+		key := SubscriptionKey(pattern, r.Currency)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], r)
+	}
+
+	var dismissed []string
+	db.Model(&models.SubscriptionDismissal{}).Where("user_id = ?", userID).Pluck("key", &dismissed)
+	isDismissed := map[string]bool{}
+	for _, k := range dismissed {
+		isDismissed[k] = true
+	}
+
+	today := truncateDay(now)
+	result := []SuspectedSubscription{}
+	for _, key := range keys {
+		s, ok := detectSubscription(groups[key], today)
+		if !ok {
+			continue
+		}
+		s.Key = key
+		s.Dismissed = isDismissed[key]
+		result = append(result, s)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].NextExpectedDate < result[j].NextExpectedDate
+	})
+	return result, nil
+}
+
+// detectSubscription memeriksa satu kelompok transaksi (urut tanggal)
+func detectSubscription(rows []subscriptionRow, today time.Time) (SuspectedSubscription, bool) {
+	if len(rows) < 2 {
+		return SuspectedSubscription{}, false
+	}
+	gaps := make([]int, 0, len(rows)-1)
+	for i := 1; i < len(rows); i++ {
+		d := truncateDay(rows[i].Date).Sub(truncateDay(rows[i-1].Date))
+		gaps = append(gaps, int(d.Hours()/24))
+	}
+	cad, ok := matchCadence(gaps)
+	if !ok || len(rows) < cad.MinCount {
+		return SuspectedSubscription{}, false
+	}
+
+	// nominal mirip: minimal 3/4 transaksi berada dalam ±25% dari median
+	amounts := make([]money.Amount, len(rows))
+	var sum money.Amount
+	for i, r := range rows {
+		amounts[i] = r.Amount
+		sum += r.Amount
+	}
+	sort.Slice(amounts, func(i, j int) bool { return amounts[i] < amounts[j] })
+	median := amounts[len(amounts)/2]
+	similar := 0
+	for _, a := range amounts {
+		diff := a - median
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff*4 <= median {
+			similar++
+		}
+	}
+	if float64(similar) < subscriptionRegularShare*float64(len(rows)) {
+		return SuspectedSubscription{}, false
+	}
+
+	last := rows[len(rows)-1]
+	lastDay := truncateDay(last.Date)
+	// berhenti berlangganan: tidak ada transaksi selama lebih dari dua periode
+	if today.Sub(lastDay) > time.Duration(2*cad.MaxDays)*24*time.Hour {
+		return SuspectedSubscription{}, false
+	}
+
+	// jadwal usulan: dihitung dari transaksi terakhir dengan aturan yang sama seperti template recurring
+	rec := models.Recurrence{Frequency: cad.Frequency, Interval: cad.Interval, StartDate: lastDay}
+	if cad.Frequency == RecurMonthly {
+		rec.MonthDay = lastDay.Day()
+	}
+	n := FirstIndexAfter(&rec, today.AddDate(0, 0, -1))
+	if n < 1 {
+		n = 1
+	}
+	next := OccurrenceDate(&rec, n)
+	rec.StartDate = next
+
+	count := money.Amount(len(rows))
+	avg := (sum + count/2) / count
+	ids := make([]uint, len(rows))
+	for i, r := range rows {
+		ids[i] = r.ID
+	}
+	return SuspectedSubscription{
+		Pattern:          NormalizeSubscriptionNote(last.Note),
+		Name:             last.Note,
+		Currency:         last.Currency,
+		CategoryID:       last.CategoryID,
+		AccountID:        last.AccountID,
+		Cadence:          cad.Name,
+		Occurrences:      len(rows),
+		AverageAmount:    avg,
+		LastAmount:       last.Amount,
+		LastDate:         lastDay.Format("2006-01-02"),
+		NextExpectedDate: next.Format("2006-01-02"),
+		AnnualizedCost:   avg * money.Amount(cad.PerYear),
+		TransactionIDs:   ids,
+		Recurrence:       rec,
+	}, true
+}
+
+// matchCadence memilih cadence dari median jarak, lalu memastikan sebagian besar jarak sesuai
+func matchCadence(gaps []int) (subscriptionCadence, bool) {
+	sorted := append([]int(nil), gaps...)
+	sort.Ints(sorted)
+	median := sorted[len(sorted)/2]
+	for _, cad := range subscriptionCadences {
+		if median < cad.MinDays || median > cad.MaxDays {
+			continue
+		}
+		regular := 0
+		for _, g := range gaps {
+			if g >= cad.MinDays && g <= cad.MaxDays {
+				regular++
+			}
+		}
+		if float64(regular) >= subscriptionRegularShare*float64(len(gaps)) {
+			return cad, true
+		}
+		return subscriptionCadence{}, false
+	}
+	return subscriptionCadence{}, false
+}
+
+// FindSubscription mencari satu hasil deteksi berdasarkan key
+func FindSubscription(db *gorm.DB, userID uint, key string, now time.Time) (*SuspectedSubscription, error) {
+	list, err := DetectSubscriptions(db, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if list[i].Key == key {
+			return &list[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
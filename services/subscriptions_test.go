@@ -0,0 +1,124 @@
+// services/subscriptions_test.go
+package services
+
+import (
+	"testing"
+	"time"
+
+	"finance/money"
+)
+
+// subRows membentuk transaksi dengan catatan sama; dates "2006-01-02", amounts dalam rupiah utuh
+func subRows(dates []string, amounts ...int64) []subscriptionRow {
+	rows := make([]subscriptionRow, len(dates))
+	for i, d := range dates {
+		amount := amounts[0]
+		if i < len(amounts) {
+			amount = amounts[i]
+		}
+		rows[i] = subscriptionRow{ID: uint(i + 1), Date: day(d).Add(19 * time.Hour), Amount: money.FromInt(amount), Currency: "IDR", Note: "NETFLIX.COM #" + d, CategoryID: 3}
+	}
+	return rows
+}
+
+func TestNormalizeSubscriptionNote(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"NETFLIX.COM 10/2026 #8812", "netflix com"},
+		{"Netflix.com 11/2026", "netflix com"},
+		{"  Spotify   Premium!! ", "spotify premium"},
+		{"Café Olé - 2x", "café olé x"},
+		{"12/10 #99", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeSubscriptionNote(tt.in); got != tt.want {
+			t.Errorf("NormalizeSubscriptionNote(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMatchCadence(t *testing.T) {
+	tests := []struct {
+		name string
+		gaps []int
+		want string // "" = tidak cocok
+	}{
+		{"weekly", []int{7, 7, 7}, "weekly"},
+		{"weekly bounds", []int{6, 8, 6}, "weekly"},
+		{"below weekly", []int{5, 5, 5}, ""},
+		{"between weekly and biweekly", []int{9, 10, 11}, ""},
+		{"biweekly bounds", []int{12, 16}, "biweekly"},
+		{"monthly bounds", []int{26, 35, 31}, "monthly"},
+		{"just past monthly", []int{36, 36, 36}, ""},
+		{"quarterly", []int{84, 98, 91}, "quarterly"},
+		{"yearly", []int{350, 380}, "yearly"},
+		{"exactly 75% regular", []int{30, 30, 60, 31}, "monthly"},
+		{"6 of 8 regular", []int{30, 30, 10, 30, 30, 10, 30, 30}, "monthly"},
+		{"below 75% regular", []int{30, 10, 30, 10, 30}, ""},
+		{"median decides cadence", []int{7, 30, 30}, ""}, // median 30, tapi hanya 2/3 jarak bulanan
+	}
+	for _, tt := range tests {
+		cad, ok := matchCadence(tt.gaps)
+		if got := map[bool]string{true: cad.Name}[ok]; got != tt.want {
+			t.Errorf("%s: matchCadence(%v) = %q, want %q", tt.name, tt.gaps, got, tt.want)
+		}
+	}
+}
+
+func TestDetectSubscription(t *testing.T) {
+	monthly := []string{"2026-01-15", "2026-02-15", "2026-03-15", "2026-04-15"}
+	tests := []struct {
+		name    string
+		rows    []subscriptionRow
+		today   string
+		ok      bool
+		cadence string
+		avg     money.Amount
+		next    string
+	}{
+		{"monthly", subRows(monthly, 54000), "2026-04-20", true, "monthly", money.FromInt(54000), "2026-05-15"},
+		{"next expected is today", subRows(monthly, 54000), "2026-05-15", true, "monthly", money.FromInt(54000), "2026-05-15"},
+		{"too few for monthly", subRows(monthly[:2], 54000), "2026-02-20", false, "", 0, ""},
+		{"yearly needs two", subRows([]string{"2025-03-01", "2026-03-01"}, 990000), "2026-03-02", true, "yearly", money.FromInt(990000), "2027-03-01"},
+		{"month end clamps", subRows([]string{"2025-11-30", "2025-12-31", "2026-01-31"}, 100), "2026-02-01", true, "monthly", money.FromInt(100), "2026-02-28"},
+
+		// nominal: minimal 3/4 dalam ±25% dari median, rata-rata tetap memakai semua transaksi
+		{"one outlier of four", subRows(monthly, 100, 100, 100, 500), "2026-04-20", true, "monthly", money.FromInt(200), "2026-05-15"},
+		{"two outliers of five", subRows(append(monthly, "2026-05-15"), 100, 100, 100, 500, 500), "2026-05-20", false, "", 0, ""},
+		{"price change of exactly 25%", subRows(append(monthly, "2026-05-15"), 10000, 10000, 10000, 12500, 12500), "2026-05-20", true, "monthly", money.FromInt(11000), "2026-06-15"},
+		{"price change above 25%", subRows(append(monthly, "2026-05-15"), 10000, 10000, 10000, 12501, 12501), "2026-05-20", false, "", 0, ""},
+
+		// berhenti: lebih dari dua periode terpanjang (2 x 35 hari) sejak transaksi terakhir
+		{"70 days after last", subRows(monthly, 54000), "2026-06-24", true, "monthly", money.FromInt(54000), "2026-07-15"},
+		{"71 days after last", subRows(monthly, 54000), "2026-06-25", false, "", 0, ""},
+
+		{"irregular gaps", subRows([]string{"2026-01-01", "2026-01-05", "2026-02-20", "2026-02-27"}, 100), "2026-03-01", false, "", 0, ""},
+	}
+	for _, tt := range tests {
+		s, ok := detectSubscription(tt.rows, day(tt.today))
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if s.Cadence != tt.cadence || s.AverageAmount != tt.avg || s.NextExpectedDate != tt.next {
+			t.Errorf("%s: got cadence=%s avg=%s next=%s, want %s %s %s", tt.name, s.Cadence, s.AverageAmount, s.NextExpectedDate, tt.cadence, tt.avg, tt.next)
+		}
+		if s.Occurrences != len(tt.rows) || len(s.TransactionIDs) != len(tt.rows) {
+			t.Errorf("%s: occurrences = %d, ids = %v", tt.name, s.Occurrences, s.TransactionIDs)
+		}
+		if s.Pattern != "netflix com" {
+			t.Errorf("%s: pattern = %q", tt.name, s.Pattern)
+		}
+		if s.Recurrence.StartDate.Format("2006-01-02") != tt.next {
+			t.Errorf("%s: proposed recurrence starts %s, want %s", tt.name, s.Recurrence.StartDate.Format("2006-01-02"), tt.next)
+		}
+	}
+
+	s, _ := detectSubscription(subRows(monthly, 54000), day("2026-04-20"))
+	if s.AnnualizedCost != money.FromInt(54000*12) {
+		t.Errorf("annualized cost = %s, want %s", s.AnnualizedCost, money.FromInt(54000*12))
+	}
+}
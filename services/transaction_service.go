@@ -0,0 +1,172 @@
+// services/transaction_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"finance/models"
+	"finance/money"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCategory  = errors.New("invalid category")
+	ErrArchivedCategory = errors.New("category is archived")
+	ErrInvalidAccount   = errors.New("invalid account")
+	ErrInvalidCurrency  = errors.New("currency must be a 3-letter ISO code")
+	ErrCurrencyMismatch = errors.New("currency must match account currency")
+)
+
+// NewTransaction adalah data transaksi baru, baik dari API, template recurring, maupun tagihan
+type NewTransaction struct {
+	CategoryID uint
+	AccountID  *uint
+	Amount     money.Amount
+	Date       time.Time
+	Note       string
+	Currency   string // kosong = mata uang akun, atau mata uang dasar user bila tanpa akun
+	Splits     []SplitLine
+}
+
+// BuildTransaction memvalidasi kategori (atau split line), akun dan mata uang, lalu membentuk
+// transaksi yang siap disimpan beserta kategori utamanya. Semua error adalah error validasi.
+func BuildTransaction(db *gorm.DB, userID uint, in NewTransaction) (*models.Transaction, *models.Category, error) {
+	// kategori; untuk split: kategori baris pertama
+	var cat models.Category
+	if len(in.Splits) > 0 {
+		first, err := ValidateSplits(db, userID, in.Amount, in.Splits)
+		if err != nil {
+			return nil, nil, err
+		}
+		cat = *first
+	} else if err := db.Where("id = ? AND user_id = ?", in.CategoryID, userID).First(&cat).Error; err != nil {
+		return nil, nil, ErrInvalidCategory
+	}
+	ids := []uint{cat.ID}
+	for _, l := range in.Splits {
+		ids = append(ids, l.CategoryID)
+	}
+	if err := CheckCategoriesActive(db, userID, ids...); err != nil {
+		return nil, nil, err
+	}
+
+	// akun (opsional); mata uang ikut akun
+	currency := strings.ToUpper(in.Currency)
+	if in.AccountID != nil {
+		var acc models.Account
+		if err := db.Where("id = ? AND user_id = ?", *in.AccountID, userID).First(&acc).Error; err != nil {
+			return nil, nil, ErrInvalidAccount
+		}
+		if currency == "" {
+			currency = acc.Currency
+		} else if currency != acc.Currency {
+			return nil, nil, fmt.Errorf("%w %s", ErrCurrencyMismatch, acc.Currency)
+		}
+	}
+	if currency == "" {
+		currency = BaseCurrency(db, userID)
+	}
+	if !ValidCurrency(currency) {
+		return nil, nil, ErrInvalidCurrency
+	}
+
+	trx := &models.Transaction{
+		UserID:     userID,
+		CategoryID: cat.ID,
+		AccountID:  in.AccountID,
+		Currency:   currency,
+		Amount:     in.Amount,
+		Date:       in.Date,
+		Note:       in.Note,
+	}
+	return trx, &cat, nil
+}
+
+// CheckCategoriesActive menolak kategori arsip untuk data baru; transaksi lama tetap memakainya
+func CheckCategoriesActive(db *gorm.DB, userID uint, ids ...uint) error {
+	var archived int64
+	db.Model(&models.Category{}).Where("id IN ? AND user_id = ? AND archived_at IS NOT NULL", ids, userID).Count(&archived)
+	if archived > 0 {
+		return ErrArchivedCategory
+	}
+	return nil
+}
+
+// IsTransactionInputError: error validasi dari BuildTransaction (ditampilkan ke user sebagai 400)
+func IsTransactionInputError(err error) bool {
+	for _, target := range []error{ErrInvalidCategory, ErrArchivedCategory, ErrInvalidAccount, ErrInvalidCurrency, ErrCurrencyMismatch, ErrInvalidSplit} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateTransaction menyimpan transaksi beserta split line-nya. Panggil di dalam db.Transaction.
+func CreateTransaction(tx *gorm.DB, trx *models.Transaction, splits []SplitLine) error {
+	if err := tx.Create(trx).Error; err != nil {
+		return err
+	}
+	return ReplaceSplits(tx, trx, splits)
+}
+
+// BudgetStatus menghitung status budget (80% dihitung dengan integer: total*5 >= limit*4)
+func BudgetStatus(total, limit money.Amount) string {
+	if total >= limit {
+		return "Over Budget"
+	} else if total*5 >= limit*4 {
+		return "Near Limit"
+	}
+	return "Safe"
+}
+
+// CheckCategoryBudget menghitung pemakaian budget kategori (termasuk split line) setelah
+// transaksi baru dicatat, dan membuat notifikasi bila sudah melebihi batas. Budget kategori
+// induk juga diperiksa karena ikut menghitung subkategori; yang dikembalikan adalah budget
+// terdekat. ok = false bila kategori maupun induknya tidak punya budget.
+func CheckCategoryBudget(db *gorm.DB, userID uint, cat models.Category) (status string, total money.Amount, ok bool) {
+	base := ""
+	for _, c := range CategoryAncestors(db, cat) {
+		var budget models.Budget
+		if err := db.Where("category_id = ? AND user_id = ?", c.ID, userID).First(&budget).Error; err != nil {
+			continue
+		}
+		if base == "" {
+			base = BaseCurrency(db, userID)
+			EnsureRates(db, userID, base)
+		}
+		var spent money.Amount
+		db.Raw(`
+            SELECT COALESCE(SUM(`+ConvertSQL("tl.amount", "tl.currency", "tl.date", base)+`),0)
+            FROM `+CategoryLinesSQL+` tl
+            WHERE `+InCategoryTreeSQL("tl.category_id", "?")+` AND tl.user_id = ? AND tl.date BETWEEN ? AND ?
+        `, c.ID, userID, budget.StartDate, budget.EndDate).Scan(&spent)
+
+		s := BudgetStatus(spent, budget.LimitAmount)
+		if s == "Over Budget" {
+			if err := AddBudgetNotification(db, userID, c.Name); err != nil {
+				log.Println("Gagal simpan notifikasi:", err)
+			}
+		}
+		if !ok {
+			status, total, ok = s, spent, true
+		}
+	}
+	return status, total, ok
+}
+
+// AddBudgetNotification membuat notifikasi bahwa budget kategori sudah terlampaui
+func AddBudgetNotification(db *gorm.DB, userID uint, category string) error {
+	notif := models.Notification{
+		UserID:    userID,
+		Title:     "Budget Alert",
+		Message:   fmt.Sprintf("Kategori %s sudah melebihi batas!", category),
+		CreatedAt: time.Now(),
+	}
+	return db.Create(&notif).Error
+}
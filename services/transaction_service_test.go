@@ -0,0 +1,42 @@
+// services/transaction_service_test.go
+package services
+
+import (
+	"testing"
+
+	"finance/money"
+)
+
+func TestBudgetStatus(t *testing.T) {
+	limit := money.FromInt(1000)
+	tests := []struct {
+		total string
+		want  string
+	}{
+		{"0", "Safe"},
+		{"799.99", "Safe"},
+		{"800.00", "Near Limit"}, // tepat 80%
+		{"800.01", "Near Limit"},
+		{"999.99", "Near Limit"},
+		{"1000.00", "Over Budget"},
+		{"1000.01", "Over Budget"},
+	}
+	for _, tt := range tests {
+		total, err := money.Parse(tt.total)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := BudgetStatus(total, limit); got != tt.want {
+			t.Errorf("BudgetStatus(%s, %s) = %q, want %q", total, limit, got, tt.want)
+		}
+	}
+
+	// 80% dari limit yang tidak habis dibagi 5: 10.01 * 0.8 = 8.008
+	odd := money.FromMinor(1001)
+	if got := BudgetStatus(money.FromMinor(800), odd); got != "Safe" {
+		t.Errorf("8.00 of 10.01 = %q, want Safe", got)
+	}
+	if got := BudgetStatus(money.FromMinor(801), odd); got != "Near Limit" {
+		t.Errorf("8.01 of 10.01 = %q, want Near Limit", got)
+	}
+}
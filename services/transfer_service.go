@@ -0,0 +1,69 @@
+// services/transfer_service.go
+package services
+
+import (
+	"finance/models"
+
+	"gorm.io/gorm"
+)
+
+// CreateTransfer menyimpan transfer beserta kedua leg-nya. Jalankan di dalam transaksi DB.
+func CreateTransfer(tx *gorm.DB, t *models.Transfer) error {
+	if err := tx.Create(t).Error; err != nil {
+		return err
+	}
+	for _, leg := range transferLegs(t) {
+		if err := tx.Create(&leg).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateTransfer menyimpan perubahan transfer dan menyamakan kedua leg-nya
+func UpdateTransfer(tx *gorm.DB, t *models.Transfer) error {
+	if err := tx.Save(t).Error; err != nil {
+		return err
+	}
+	for _, leg := range transferLegs(t) {
+		err := tx.Model(&models.Transaction{}).
+			Where("transfer_id = ? AND transfer_leg = ?", t.ID, leg.TransferLeg).
+			Updates(map[string]interface{}{
+				"account_id": leg.AccountID,
+				"amount":     leg.Amount,
+				"date":       leg.Date,
+				"note":       leg.Note,
+				"currency":   leg.Currency,
+			}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteTransfer menghapus transfer beserta kedua leg-nya
+func DeleteTransfer(tx *gorm.DB, t *models.Transfer) error {
+	if err := tx.Where("transfer_id = ?", t.ID).Delete(&models.Transaction{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(t).Error
+}
+
+// transferLegs membentuk leg keluar (akun asal) dan leg masuk (akun tujuan)
+func transferLegs(t *models.Transfer) []models.Transaction {
+	from, to := t.FromAccountID, t.ToAccountID
+	leg := func(accountID *uint, side string) models.Transaction {
+		return models.Transaction{
+			UserID:      t.UserID,
+			AccountID:   accountID,
+			Amount:      t.Amount,
+			Date:        t.Date,
+			Note:        t.Note,
+			Currency:    t.Currency,
+			TransferID:  &t.ID,
+			TransferLeg: side,
+		}
+	}
+	return []models.Transaction{leg(&from, models.TransferOut), leg(&to, models.TransferIn)}
+}
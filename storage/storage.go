@@ -0,0 +1,161 @@
+// storage/storage.go
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Storage menyimpan file (mis. arsip backup) dan mengembalikan URL untuk mengambilnya lagi.
+// Implementasi dipilih lewat env STORAGE_DRIVER.
+type Storage interface {
+	Put(key string, data []byte) (string, error)
+	Get(url string) ([]byte, error)
+	Delete(url string) error
+}
+
+var Default Storage
+
+// Init memilih backend storage dari environment:
+//   - STORAGE_DRIVER=memory: disimpan di memori (development)
+//   - selain itu: file lokal di STORAGE_DIR (default ./storage_data)
+func Init() {
+	switch os.Getenv("STORAGE_DRIVER") {
+	case "memory":
+		Default = NewMemoryStorage()
+	default:
+		dir := os.Getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = "./storage_data"
+		}
+		Default = &LocalStorage{Dir: dir}
+	}
+}
+
+func get() Storage {
+	if Default == nil {
+		Init()
+	}
+	return Default
+}
+
+// Put, Get dan Delete memakai Default storage
+func Put(key string, data []byte) (string, error) { return get().Put(key, data) }
+func Get(url string) ([]byte, error)              { return get().Get(url) }
+func Delete(url string) error                     { return get().Delete(url) }
+
+// cleanKey menolak key yang keluar dari direktori storage
+func cleanKey(key string) (string, error) {
+	key = filepath.ToSlash(filepath.Clean("/" + key))[1:]
+	if key == "" || key == "." {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return key, nil
+}
+
+// LocalStorage menyimpan file di disk dengan URL berbentuk "local://<key>"
+type LocalStorage struct {
+	Dir string
+}
+
+const localScheme = "local://"
+
+func (s *LocalStorage) path(url string) (string, error) {
+	if !strings.HasPrefix(url, localScheme) {
+		return "", fmt.Errorf("unsupported storage url %q", url)
+	}
+	key, err := cleanKey(strings.TrimPrefix(url, localScheme))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
+
+func (s *LocalStorage) Put(key string, data []byte) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(s.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	// Tulis ke file sementara dulu agar arsip tidak pernah setengah jadi
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return localScheme + key, nil
+}
+
+func (s *LocalStorage) Get(url string) ([]byte, error) {
+	path, err := s.path(url)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *LocalStorage) Delete(url string) error {
+	path, err := s.path(url)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// MemoryStorage menyimpan file di memori dengan URL berbentuk "memory://<key>"
+type MemoryStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{objects: map[string][]byte{}}
+}
+
+func (s *MemoryStorage) Put(key string, data []byte) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	url := "memory://" + key
+	s.mu.Lock()
+	s.objects[url] = append([]byte(nil), data...)
+	s.mu.Unlock()
+	return url, nil
+}
+
+func (s *MemoryStorage) Get(url string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[url]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (s *MemoryStorage) Delete(url string) error {
+	s.mu.Lock()
+	delete(s.objects, url)
+	s.mu.Unlock()
+	return nil
+}
@@ -4,17 +4,58 @@ package utils
 import (
 	"errors"
 
+	"finance/models"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// GetUserID mengambil user dari access token atau personal access token.
+// Personal access token hanya diterima di route yang memasang middleware.Scope.
 func GetUserID(c *fiber.Ctx) (uint, error) {
+	if pat := GetPAT(c); pat != nil && c.Locals("pat_scoped") != true {
+		return 0, errors.New("personal access token not allowed on this route")
+	}
+	return AuthUserID(c)
+}
+
+// AuthUserID mengambil user yang terautentikasi tanpa memeriksa scope (untuk middleware)
+func AuthUserID(c *fiber.Ctx) (uint, error) {
+	if pat := GetPAT(c); pat != nil {
+		return pat.UserID, nil
+	}
+	return uintClaim(c, "user_id")
+}
+
+// GetPAT mengembalikan personal access token yang dipakai request ini (nil untuk JWT)
+func GetPAT(c *fiber.Ctx) *models.PersonalAccessToken {
+	pat, _ := c.Locals("pat").(*models.PersonalAccessToken)
+	return pat
+}
+
+// GetRole mengambil claim "role" dari access token ("" untuk personal access token)
+func GetRole(c *fiber.Ctx) string {
+	token, ok := c.Locals("jwt").(*jwt.Token)
+	if !ok || token == nil || GetPAT(c) != nil {
+		return ""
+	}
+	claims, _ := token.Claims.(jwt.MapClaims)
+	role, _ := claims["role"].(string)
+	return role
+}
+
+// GetSessionID mengambil id sesi (claim "sid") dari access token
+func GetSessionID(c *fiber.Ctx) (uint, error) {
+	return uintClaim(c, "sid")
+}
+
+func uintClaim(c *fiber.Ctx, key string) (uint, error) {
 	token, ok := c.Locals("jwt").(*jwt.Token)
 	if !ok || token == nil {
 		return 0, errors.New("no jwt token")
 	}
 	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		if v, ok := claims["user_id"]; ok {
+		if v, ok := claims[key]; ok {
 			switch t := v.(type) {
 			case float64:
 				return uint(t), nil
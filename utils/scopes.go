@@ -0,0 +1,41 @@
+// utils/scopes.go
+package utils
+
+import "strings"
+
+// PATPrefix menandai personal access token agar bisa dibedakan dari JWT
+const PATPrefix = "pat_"
+
+// Scopes adalah daftar scope yang bisa diberikan ke personal access token.
+// Scope ":write" otomatis mencakup ":read" untuk resource yang sama.
+var Scopes = []string{
+	"categories:read", "categories:write",
+	"transactions:read", "transactions:write",
+	"accounts:read", "accounts:write",
+	"reports:read",
+	"budgets:read", "budgets:write",
+	"bills:read", "bills:write",
+	"notifications:read", "notifications:write",
+	"profile:read", "profile:write",
+	"backups:read", "backups:write",
+}
+
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope mengecek scope di dalam daftar scope yang dipisah spasi
+func HasScope(granted, scope string) bool {
+	resource, action, _ := strings.Cut(scope, ":")
+	for _, g := range strings.Fields(granted) {
+		if g == scope || (action == "read" && g == resource+":write") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+// utils/totp_test.go
+package utils
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 Appendix B, mode SHA1 (kode 8 digit; yang dipakai 6 digit terakhir)
+var rfc6238Key = []byte("12345678901234567890")
+
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "94287082"},
+	{1111111109, "07081804"},
+	{1111111111, "14050471"},
+	{1234567890, "89005924"},
+	{2000000000, "69279037"},
+	{20000000000, "65353130"},
+}
+
+func TestTOTPAtRFC6238(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		want := v.code[2:]
+		if got := totpAt(rfc6238Key, v.unix/totpPeriod); got != want {
+			t.Errorf("T=%d: totpAt = %s, want %s", v.unix, got, want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := b32.EncodeToString(rfc6238Key)
+	for _, v := range rfc6238Vectors {
+		now := time.Unix(v.unix, 0)
+		code := v.code[2:]
+		step, ok := ValidateTOTP(secret, code, now, 0)
+		if !ok || step != v.unix/totpPeriod {
+			t.Errorf("T=%d: ValidateTOTP = %d, %v", v.unix, step, ok)
+			continue
+		}
+		// kode yang sama tidak bisa dipakai dua kali
+		if _, ok := ValidateTOTP(secret, code, now, step); ok {
+			t.Errorf("T=%d: replay accepted", v.unix)
+		}
+		// toleransi satu langkah sebelum/sesudah, tidak lebih
+		if _, ok := ValidateTOTP(secret, code, now.Add(totpPeriod*time.Second), 0); !ok {
+			t.Errorf("T=%d: code from previous step rejected", v.unix)
+		}
+		if _, ok := ValidateTOTP(secret, code, now.Add(-totpPeriod*time.Second), 0); !ok {
+			t.Errorf("T=%d: code from next step rejected", v.unix)
+		}
+		if _, ok := ValidateTOTP(secret, code, now.Add(2*totpPeriod*time.Second), 0); ok {
+			t.Errorf("T=%d: code two steps old accepted", v.unix)
+		}
+	}
+
+	now := time.Unix(59, 0)
+	if _, ok := ValidateTOTP(strings.ToLower(secret), "287082", now, 0); !ok {
+		t.Error("lowercase secret should be accepted")
+	}
+	for _, code := range []string{"94287082", "28708", "287083", ""} {
+		if _, ok := ValidateTOTP(secret, code, now, 0); ok {
+			t.Errorf("code %q accepted", code)
+		}
+	}
+	if _, ok := ValidateTOTP("not base32!", "287082", now, 0); ok {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	u, err := url.Parse(TOTPURI("Finance", "a@b.c", "JBSWY3DPEHPK3PXP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Finance:a@b.c" {
+		t.Errorf("unexpected uri %s", u)
+	}
+	if q.Get("secret") != "JBSWY3DPEHPK3PXP" || q.Get("digits") != "6" || q.Get("period") != "30" || q.Get("algorithm") != "SHA1" {
+		t.Errorf("unexpected params %v", q)
+	}
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	s, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := b32.DecodeString(s)
+	if err != nil || len(key) != 20 {
+		t.Errorf("secret %q decodes to %d bytes (%v), want 20", s, len(key), err)
+	}
+}
@@ -0,0 +1,16 @@
+// utils/uploads.go
+package utils
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// UploadPath mengubah URL "/uploads/<file>" menjadi path lokal; "" untuk URL eksternal
+func UploadPath(publicURL string) string {
+	name, ok := strings.CutPrefix(publicURL, "/uploads/")
+	if !ok || name == "" {
+		return ""
+	}
+	return filepath.Join("./uploads", filepath.Base(name))
+}